@@ -2,14 +2,31 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"time"
+	_ "time/tzdata" // Встроенная база часовых поясов пользователей для систем без tzdata.
+
+	"github.com/jmoiron/sqlx"
 
 	"github.com/shoksin/financesBot/internal/metrics"
 	"github.com/shoksin/financesBot/internal/tracing"
 
+	"github.com/shoksin/financesBot/internal/clients/ecb"
+	"github.com/shoksin/financesBot/internal/clients/kafka"
+	"github.com/shoksin/financesBot/internal/clients/memqueue"
+	"github.com/shoksin/financesBot/internal/clients/nbrb"
+	"github.com/shoksin/financesBot/internal/clients/pgqueue"
 	"github.com/shoksin/financesBot/internal/clients/tg"
 	"github.com/shoksin/financesBot/internal/config"
+	"github.com/shoksin/financesBot/internal/helpers/dbutils"
+	"github.com/shoksin/financesBot/internal/helpers/lrucache"
+	"github.com/shoksin/financesBot/internal/helpers/money"
 	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/currencies"
+	dbstorage "github.com/shoksin/financesBot/internal/models/db"
+	"github.com/shoksin/financesBot/internal/models/messages"
+	"github.com/shoksin/financesBot/internal/models/ratealerts"
+	"github.com/shoksin/financesBot/internal/models/reports"
 )
 
 // default settings
@@ -19,8 +36,21 @@ var (
 	currenciesUpdatePeriod      = 30 * time.Minute //Периодичность обновления курсов валют (раз в 30 минут).
 	currenciesUpdateCachePeriod = 30 * time.Minute //Периодичность кэширования курсов валют из базы данных (раз в 30 минут).
 	connectionStringDB          = ""
+	defaultTimezone             = "Europe/Minsk" //Часовой пояс пользователей, не задавших свой.
 	kafkaTopic                  = "tgbot"
 	brokersList                 = []string{"localhost:9092"} //Список адресов брокеров сообщений (адрес Kafka)
+	reportQueue                 = "kafka"                    //Очередь запросов на формирование отчетов (kafka, memory или postgres).
+	reportQueueSize             = 100                        //Размер очереди запросов в памяти.
+	reportWorkers               = 4                          //Количество обработчиков очереди запросов в процессе бота (memory, postgres).
+	reportTimeout               = 5 * time.Minute            //Время формирования отчета, после которого пользователь получает сообщение об ошибке.
+	reportTimeoutCheckPeriod    = time.Minute                //Периодичность проверки зависших запросов на формирование отчетов.
+	digestCheckPeriod           = time.Minute                //Периодичность проверки подписок на регулярные отчеты.
+	ratesProvider               = "nbrb"                     //Источник курсов валют (nbrb или ecb).
+	nbrbURL                     = nbrb.DefaultBaseURL        //Адрес API курсов валют НБРБ.
+	ecbURL                      = ecb.DefaultBaseURL         //Адрес публикации курсов ЕЦБ.
+	ratesMaxAge                 = 24 * time.Hour             //Возраст курсов валют, после которого они считаются устаревшими.
+	reportCacheSize             = 1000                       //Количество отчетов в кэше.
+	reportCacheTTL              = 10 * time.Minute           //Время жизни отчета в кэше.
 )
 
 func main() {
@@ -47,7 +77,43 @@ func main() {
 	}
 
 	//Инициализация хранилищ (подключение к базе данных)
-	
+	db, err := dbutils.NewDBConnect(connectionStringDB)
+	if err != nil {
+		logger.Fatal("Error connecting to DB:", "err", err)
+	}
+	defer db.Close()
+
+	ratesStorage := dbstorage.NewRatesStorage(db)
+	userStorage := dbstorage.NewUserStorage(db, mainCurrency, money.Zero(mainCurrency), defaultTimezone)
+
+	// Инициализация сервиса курсов валют.
+	exchangeRates := currencies.New(newRatesProvider(), ratesStorage, mainCurrency, currenciesName, ratesMaxAge)
+
+	// Проверка подписок на курсы валют после каждого обновления курсов.
+	rateAlerts := ratealerts.New(userStorage, tgClient, exchangeRates)
+	exchangeRates.AddUpdateListener(rateAlerts.CheckAlerts)
+
+	go exchangeRates.AutoUpdate(ctx, currenciesUpdatePeriod)
+	go exchangeRates.AutoUpdateFromStorage(ctx, currenciesUpdateCachePeriod)
+
+	// Инициализация очереди запросов на формирование отчетов и кэша отчетов, сформированных в процессе бота.
+	reportProducer, reportCache, err := newReportProducer(ctx, db, tgClient, userStorage, exchangeRates)
+	if err != nil {
+		logger.Fatal("Error initializing report queue:", "err", err)
+	}
+	defer reportProducer.Close()
+
+	// Уведомление пользователей о запросах отчетов, не выполненных за отведенное время.
+	reportTimeouts := reports.NewTimeoutChecker(userStorage, tgClient, reportTimeout)
+	go reportTimeouts.AutoCheckTimeouts(ctx, reportTimeoutCheckPeriod)
+
+	// Постановка в очередь регулярных отчетов по подпискам пользователей.
+	digestScheduler := reports.NewDigestScheduler(userStorage, reportProducer)
+	go digestScheduler.AutoRun(ctx, digestCheckPeriod)
+
+	// Инициализация модели бота и запуск обработки сообщений.
+	msgModel := messages.New(ctx, tgClient, userStorage, exchangeRates, reportCache, reportProducer)
+	tgClient.ListenUpdates(msgModel)
 
 	logger.Info("Application stop")
 }
@@ -62,11 +128,15 @@ func setConfig(config config.Config) {
 	}
 
 	if config.CurrenciesUpdatePeriod > 0 {
-		currenciesUpdatePeriod = time.Duration(config.CurrenciesUpdatePeriod)
+		currenciesUpdatePeriod = time.Duration(config.CurrenciesUpdatePeriod) * time.Minute
 	}
 
 	if config.CurrenciesUpdateCachePeriod > 0 {
-		currenciesUpdateCachePeriod = time.Duration(config.CurrenciesUpdateCachePeriod)
+		currenciesUpdateCachePeriod = time.Duration(config.CurrenciesUpdateCachePeriod) * time.Minute
+	}
+
+	if config.DefaultTimezone != "" {
+		defaultTimezone = config.DefaultTimezone
 	}
 
 	if config.ConnectionStringDB != "" {
@@ -80,4 +150,99 @@ func setConfig(config config.Config) {
 	if len(config.BrokersList) > 0 {
 		brokersList = config.BrokersList
 	}
+
+	if config.ReportQueue != "" {
+		reportQueue = config.ReportQueue
+	}
+
+	if config.ReportQueueSize > 0 {
+		reportQueueSize = config.ReportQueueSize
+	}
+
+	if config.ReportWorkers != nil {
+		reportWorkers = *config.ReportWorkers
+	}
+
+	if config.ReportTimeout > 0 {
+		reportTimeout = time.Duration(config.ReportTimeout) * time.Minute
+	}
+
+	if config.RatesMaxAge > 0 {
+		ratesMaxAge = time.Duration(config.RatesMaxAge) * time.Minute
+	}
+
+	if config.ReportCacheSize > 0 {
+		reportCacheSize = config.ReportCacheSize
+	}
+
+	if config.ReportCacheTTL > 0 {
+		reportCacheTTL = time.Duration(config.ReportCacheTTL) * time.Minute
+	}
+
+	if config.RatesProvider != "" {
+		ratesProvider = config.RatesProvider
+	}
+
+	if config.NbrbURL != "" {
+		nbrbURL = config.NbrbURL
+	}
+
+	if config.EcbURL != "" {
+		ecbURL = config.EcbURL
+	}
+}
+
+// reportProducer Очередь запросов на формирование отчетов.
+type reportProducer interface {
+	SendMessage(key string, value string) (partition int32, offset int64, err error)
+	GetTopic() string
+	Close() error
+}
+
+// newReportProducer Выбор очереди запросов на формирование отчетов по настройкам.
+// Для очереди в памяти отчеты формируются в процессе бота, для Kafka - сервисом report-service,
+// для очереди в Postgres - обработчиками бота (если report_workers > 0) и/или сервисом report-service.
+// Кэш отчетов возвращается, только если отчеты формируются в процессе бота: бот сбрасывает кэш при изменении
+// данных пользователя и не может сбросить кэш report-service.
+func newReportProducer(ctx context.Context, db *sqlx.DB, tgClient *tg.Client, userStorage *dbstorage.UserStorage, exchangeRates *currencies.ExchangeRates) (reportProducer, messages.LRUCache, error) {
+	switch reportQueue {
+	case "memory":
+		reportCache := lrucache.New("reports", reportCacheSize, reportCacheTTL)
+		queue := memqueue.New(kafkaTopic, reportQueueSize)
+		queue.Start(ctx, max(reportWorkers, 1), newReportBuilder(ctx, tgClient, userStorage, exchangeRates, reportCache).BuildReport)
+		return queue, reportCache, nil
+	case "postgres":
+		queue := pgqueue.New(db, connectionStringDB, kafkaTopic, pgqueue.DefaultOptions)
+		if reportWorkers == 0 {
+			return queue, nil, nil
+		}
+		reportCache := lrucache.New("reports", reportCacheSize, reportCacheTTL)
+		queue.Start(ctx, reportWorkers, newReportBuilder(ctx, tgClient, userStorage, exchangeRates, reportCache).WithRetries().BuildReport)
+		return queue, reportCache, nil
+	case "kafka":
+		producer, err := kafka.NewSyncProducer(brokersList, kafkaTopic)
+		return producer, nil, err
+	default:
+		return nil, nil, fmt.Errorf("unknown report queue %q", reportQueue)
+	}
+}
+
+// newReportBuilder Формирование отчетов обработчиками очереди в процессе бота.
+func newReportBuilder(ctx context.Context, tgClient *tg.Client, userStorage *dbstorage.UserStorage, exchangeRates *currencies.ExchangeRates, reportCache *lrucache.LRUCache) *reports.Builder {
+	// Отдельная модель для обработчиков очереди, т.к. модель бота не рассчитана на параллельные вызовы.
+	reportModel := messages.New(ctx, tgClient, userStorage, exchangeRates, nil, nil)
+	return reports.NewBuilder(userStorage, reportModel, reportCache)
+}
+
+// newRatesProvider Выбор источника курсов валют по настройкам.
+func newRatesProvider() currencies.RatesProvider {
+	switch ratesProvider {
+	case "ecb":
+		return ecb.New(ecbURL)
+	case "nbrb":
+		return nbrb.New(nbrbURL)
+	default:
+		logger.Warning("Unknown rates provider, nbrb is used", "provider", ratesProvider)
+		return nbrb.New(nbrbURL)
+	}
 }
@@ -0,0 +1,149 @@
+package main
+
+// Сервис формирования отчетов: читает запросы из топика Kafka (или очереди в Postgres) и отправляет отчеты пользователям.
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/clients/kafka"
+	"github.com/shoksin/financesBot/internal/clients/pgqueue"
+	"github.com/shoksin/financesBot/internal/clients/tg"
+	"github.com/shoksin/financesBot/internal/config"
+	"github.com/shoksin/financesBot/internal/helpers/dbutils"
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/currencies"
+	dbstorage "github.com/shoksin/financesBot/internal/models/db"
+	"github.com/shoksin/financesBot/internal/models/messages"
+	"github.com/shoksin/financesBot/internal/models/reports"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// default settings
+var (
+	mainCurrency                = "BYN"
+	currenciesName              = []string{"USD", "EUR", "RUB", "BYN"}
+	currenciesUpdateCachePeriod = 30 * time.Minute //Периодичность кэширования курсов валют из базы данных (раз в 30 минут).
+	ratesMaxAge                 = 24 * time.Hour   //Возраст курсов валют, после которого они считаются устаревшими.
+	connectionStringDB          = ""
+	defaultTimezone             = "Europe/Minsk" //Часовой пояс пользователей, не задавших свой.
+	kafkaTopic                  = "tgbot"
+	brokersList                 = []string{"localhost:9092"} //Список адресов брокеров сообщений (адрес Kafka)
+	reportQueue                 = "kafka"                    //Очередь запросов на формирование отчетов (kafka или postgres).
+	reportWorkers               = 4                          //Количество обработчиков очереди запросов в Postgres.
+)
+
+// consumerGroupID Группа потребителей запросов на формирование отчетов.
+const consumerGroupID = "report-service"
+
+func main() {
+	logger.Info("Report service start")
+
+	// Остановка по SIGINT/SIGTERM: обработчики очереди завершают текущие запросы, соединения закрываются.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config, err := config.New()
+	if err != nil {
+		logger.Fatal("Error to get config", "err", err)
+	}
+
+	setConfig(config.GetConfig())
+
+	// Восстановление контекста трассировки бота из запросов на формирование отчетов.
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// Клиент используется только для отправки отчетов, входящие сообщения обрабатывает бот.
+	tgClient, err := tg.New(config, nil)
+	if err != nil {
+		logger.Fatal("Error initialazing tg-client:", "err", err)
+	}
+
+	//Инициализация хранилищ (подключение к базе данных)
+	db, err := dbutils.NewDBConnect(connectionStringDB)
+	if err != nil {
+		logger.Fatal("Error connecting to DB:", "err", err)
+	}
+	defer db.Close()
+
+	ratesStorage := dbstorage.NewRatesStorage(db)
+	userStorage := dbstorage.NewUserStorage(db, mainCurrency, money.Zero(mainCurrency), defaultTimezone)
+
+	// Курсы валют обновляет бот, сервис отчетов загружает их из базы данных.
+	exchangeRates := currencies.New(nil, ratesStorage, mainCurrency, currenciesName, ratesMaxAge)
+	go exchangeRates.AutoUpdateFromStorage(ctx, currenciesUpdateCachePeriod)
+
+	// Кэша отчетов в сервисе нет: он сбрасывается при изменении данных пользователя в процессе бота,
+	// а сбросить кэш другого процесса бот не может. Отчеты, сформированные сервисом, не кэшируются.
+	msgModel := messages.New(ctx, tgClient, userStorage, exchangeRates, nil, nil)
+	reportBuilder := reports.NewBuilder(userStorage, msgModel, nil)
+
+	switch reportQueue {
+	case "postgres":
+		queue := pgqueue.New(db, connectionStringDB, kafkaTopic, pgqueue.DefaultOptions)
+		queue.Start(ctx, reportWorkers, reportBuilder.WithRetries().BuildReport)
+		<-ctx.Done()
+		queue.Close()
+	case "kafka":
+		consumer, err := kafka.NewConsumerGroup(brokersList, kafkaTopic, consumerGroupID, reportBuilder.BuildReport)
+		if err != nil {
+			logger.Fatal("Error initializing kafka consumer:", "err", err)
+		}
+		defer consumer.Close()
+
+		if err := consumer.Run(ctx); err != nil {
+			logger.Error("Error consuming report requests", "err", err)
+		}
+	default:
+		logger.Fatal("Unknown report queue", "queue", reportQueue)
+	}
+
+	logger.Info("Report service stop")
+}
+
+func setConfig(config config.Config) {
+	if config.MainCurrency != "" {
+		mainCurrency = config.MainCurrency
+	}
+
+	if len(config.CurrenciesName) > 0 {
+		currenciesName = config.CurrenciesName
+	}
+
+	if config.CurrenciesUpdateCachePeriod > 0 {
+		currenciesUpdateCachePeriod = time.Duration(config.CurrenciesUpdateCachePeriod) * time.Minute
+	}
+
+	if config.RatesMaxAge > 0 {
+		ratesMaxAge = time.Duration(config.RatesMaxAge) * time.Minute
+	}
+
+	if config.DefaultTimezone != "" {
+		defaultTimezone = config.DefaultTimezone
+	}
+
+	if config.ConnectionStringDB != "" {
+		connectionStringDB = config.ConnectionStringDB
+	}
+
+	if config.KafkaTopic != "" {
+		kafkaTopic = config.KafkaTopic
+	}
+
+	if len(config.BrokersList) > 0 {
+		brokersList = config.BrokersList
+	}
+
+	if config.ReportQueue != "" {
+		reportQueue = config.ReportQueue
+	}
+
+	if config.ReportWorkers != nil && *config.ReportWorkers > 0 {
+		reportWorkers = *config.ReportWorkers
+	}
+}
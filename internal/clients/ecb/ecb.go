@@ -0,0 +1,208 @@
+package ecb
+
+// Клиент для получения референсных курсов Европейского центрального банка.
+// Описание: https://www.ecb.europa.eu/stats/policy_and_exchange_rates/euro_reference_exchange_rates/html/index.en.html
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+// DefaultBaseURL Адрес публикации курсов ЕЦБ по умолчанию.
+const DefaultBaseURL = "https://www.ecb.europa.eu/stats/eurofxref"
+
+const (
+	dailyFeed    = "/eurofxref-daily.xml"    // Курсы за последний рабочий день.
+	hist90DFeed  = "/eurofxref-hist-90d.xml" // Курсы за последние 90 дней.
+	baseCurrency = "EUR"                     // Валюта, относительно которой ЕЦБ публикует курсы.
+)
+
+// ecbEnvelope Формат XML-ответа ЕЦБ: <Cube><Cube time="..."><Cube currency="USD" rate="1.09"/>...
+type ecbEnvelope struct {
+	Days []ecbDay `xml:"Cube>Cube"`
+}
+
+type ecbDay struct {
+	Time  string    `xml:"time,attr"`
+	Rates []ecbRate `xml:"Cube"`
+}
+
+type ecbRate struct {
+	Currency string `xml:"currency,attr"`
+	Rate     string `xml:"rate,attr"` // Количество единиц валюты за 1 EUR.
+}
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func New(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// GetExchangeRates Получение курсов валют на дату (нулевая дата - последние опубликованные курсы).
+// Если на дату курсы не публиковались (выходной, праздник), используются курсы предыдущего рабочего дня.
+func (c *Client) GetExchangeRates(ctx context.Context, mainCurrency string, currencies []string, date time.Time) (bottypes.ExchangeRate, time.Time, error) {
+	feed := dailyFeed
+	if !date.IsZero() {
+		feed = hist90DFeed
+	}
+
+	days, err := c.getFeed(ctx, feed)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	day, ok := findDay(days, date)
+	if !ok {
+		return nil, time.Time{}, fmt.Errorf("ecb has no rates for date %s", date.Format("2006-01-02"))
+	}
+
+	rates, err := rebaseRates(day, mainCurrency, currencies)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return rates, day.date, nil
+}
+
+// GetHistoricalRates Получение курсов валют за каждый календарный день периода по 90-дневной публикации.
+// Дни без публикации заполняются курсами предыдущего рабочего дня.
+func (c *Client) GetHistoricalRates(ctx context.Context, mainCurrency string, currencies []string, from, to time.Time) (map[time.Time]bottypes.ExchangeRate, error) {
+	days, err := c.getFeed(ctx, hist90DFeed)
+	if err != nil {
+		return nil, err
+	}
+
+	from = truncateDay(from)
+	to = truncateDay(to)
+
+	res := map[time.Time]bottypes.ExchangeRate{}
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		day, ok := findDay(days, date)
+		if !ok {
+			// Дата раньше начала публикации.
+			continue
+		}
+		rates, err := rebaseRates(day, mainCurrency, currencies)
+		if err != nil {
+			return nil, err
+		}
+		res[date] = rates
+	}
+	return res, nil
+}
+
+// parsedDay Курсы ЕЦБ за день (количество единиц валюты за 1 EUR).
+type parsedDay struct {
+	date  time.Time
+	rates bottypes.ExchangeRate
+}
+
+// getFeed Загрузка и разбор публикации курсов, дни отсортированы по возрастанию даты.
+func (c *Client) getFeed(ctx context.Context, feed string) ([]parsedDay, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+feed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create ecb request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ecb request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ecb request: unexpected status %s", resp.Status)
+	}
+
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("decode ecb response: %w", err)
+	}
+
+	days := make([]parsedDay, 0, len(envelope.Days))
+	for _, d := range envelope.Days {
+		date, err := time.Parse("2006-01-02", d.Time)
+		if err != nil {
+			logger.Warning("Skip ecb rates with incorrect date", "time", d.Time)
+			continue
+		}
+		rates := bottypes.ExchangeRate{baseCurrency: money.OneRate}
+		for _, r := range d.Rates {
+			rate, err := money.ParseRate(r.Rate)
+			if err != nil || !rate.IsPositive() {
+				logger.Warning("Skip incorrect ecb rate", "currency", r.Currency, "rate", r.Rate)
+				continue
+			}
+			rates[r.Currency] = rate
+		}
+		days = append(days, parsedDay{date: date, rates: rates})
+	}
+
+	if len(days) == 0 {
+		return nil, fmt.Errorf("ecb feed %s has no rates", feed)
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].date.Before(days[j].date) })
+	return days, nil
+}
+
+// findDay Поиск последнего дня публикации не позднее даты (нулевая дата - последний день).
+func findDay(days []parsedDay, date time.Time) (parsedDay, bool) {
+	if date.IsZero() {
+		return days[len(days)-1], true
+	}
+
+	date = truncateDay(date)
+	for i := len(days) - 1; i >= 0; i-- {
+		if !days[i].date.After(date) {
+			return days[i], true
+		}
+	}
+	return parsedDay{}, false
+}
+
+// rebaseRates Пересчет курсов из котировок к EUR в курсы относительно основной валюты.
+func rebaseRates(day parsedDay, mainCurrency string, currencies []string) (bottypes.ExchangeRate, error) {
+	mainRate, ok := day.rates[mainCurrency]
+	if !ok {
+		return nil, fmt.Errorf("ecb has no rate for main currency %s", mainCurrency)
+	}
+
+	rates := bottypes.ExchangeRate{}
+	for _, currency := range currencies {
+		rate, ok := day.rates[currency]
+		if !ok {
+			logger.Warning("Ecb has no rate for currency", "currency", currency)
+			continue
+		}
+		rebased, err := rate.Div(mainRate)
+		if err != nil {
+			return nil, fmt.Errorf("rebase ecb rate %s: %w", currency, err)
+		}
+		rates[currency] = rebased
+	}
+	rates[mainCurrency] = money.OneRate
+
+	return rates, nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
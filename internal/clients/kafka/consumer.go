@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/IBM/sarama"
+	"github.com/shoksin/financesBot/internal/logger"
+)
+
+// MessageHandler Функция обработки сообщения из топика.
+type MessageHandler func(ctx context.Context, key string, value string) error
+
+// ConsumerGroup Чтение сообщений из топика Kafka в составе группы потребителей.
+type ConsumerGroup struct {
+	group   sarama.ConsumerGroup
+	topic   string
+	handler MessageHandler
+}
+
+func NewConsumerGroup(brokersList []string, topic string, groupID string, handler MessageHandler) (*ConsumerGroup, error) {
+	config := sarama.NewConfig()
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRoundRobin()}
+
+	group, err := sarama.NewConsumerGroup(brokersList, groupID, config)
+	if err != nil {
+		return nil, fmt.Errorf("create kafka consumer group: %w", err)
+	}
+
+	return &ConsumerGroup{group: group, topic: topic, handler: handler}, nil
+}
+
+// Run Чтение сообщений до отмены контекста.
+func (c *ConsumerGroup) Run(ctx context.Context) error {
+	logger.Info("Start consuming kafka messages", "topic", c.topic)
+	for {
+		// Consume возвращает управление при ребалансировке группы, поэтому вызывается в цикле.
+		if err := c.group.Consume(ctx, []string{c.topic}, c); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("consume kafka messages: %w", err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (c *ConsumerGroup) Close() error {
+	return c.group.Close()
+}
+
+// Setup Реализация интерфейса sarama.ConsumerGroupHandler.
+func (c *ConsumerGroup) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// Cleanup Реализация интерфейса sarama.ConsumerGroupHandler.
+func (c *ConsumerGroup) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim Реализация интерфейса sarama.ConsumerGroupHandler: обработка сообщений партиции.
+func (c *ConsumerGroup) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			logger.Debug(fmt.Sprintf("[KAFKA] Message received, topic %s, offset: %d, partition: %d", msg.Topic, msg.Offset, msg.Partition))
+
+			if err := c.handler(session.Context(), string(msg.Key), string(msg.Value)); err != nil {
+				logger.Error("Error processing kafka message", "key", string(msg.Key), "value", string(msg.Value), "err", err)
+			}
+			session.MarkMessage(msg, "")
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/IBM/sarama"
+)
+
+// Producer Синхронный продюсер сообщений в топик Kafka.
+type Producer struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+func NewSyncProducer(brokersList []string, topic string) (*Producer, error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 5
+	config.Producer.Return.Successes = true
+
+	producer, err := sarama.NewSyncProducer(brokersList, config)
+	if err != nil {
+		return nil, fmt.Errorf("create kafka producer: %w", err)
+	}
+
+	return &Producer{producer: producer, topic: topic}, nil
+}
+
+// SendMessage Отправка сообщения в топик продюсера.
+func (p *Producer) SendMessage(key string, value string) (partition int32, offset int64, err error) {
+	msg := &sarama.ProducerMessage{
+		Topic: p.topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.StringEncoder(value),
+	}
+	return p.producer.SendMessage(msg)
+}
+
+func (p *Producer) GetTopic() string {
+	return p.topic
+}
+
+func (p *Producer) Close() error {
+	return p.producer.Close()
+}
@@ -0,0 +1,105 @@
+package memqueue
+
+// Очередь сообщений в памяти процесса с пулом обработчиков.
+// Повторяет контракт продюсера Kafka (SendMessage/GetTopic) для установок без брокера сообщений.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/shoksin/financesBot/internal/logger"
+)
+
+var (
+	ErrQueueFull   = errors.New("queue is full")
+	ErrQueueClosed = errors.New("queue is closed")
+)
+
+// MessageHandler Функция обработки сообщения из очереди.
+type MessageHandler func(ctx context.Context, key string, value string) error
+
+type message struct {
+	key    string
+	value  string
+	offset int64
+}
+
+type Queue struct {
+	topic    string
+	messages chan message
+	offset   atomic.Int64 // Порядковый номер последнего сообщения.
+	mu       sync.RWMutex
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// New Создание очереди с ограниченным размером буфера.
+func New(topic string, size int) *Queue {
+	return &Queue{
+		topic:    topic,
+		messages: make(chan message, size),
+	}
+}
+
+// Start Запуск пула обработчиков сообщений до отмены контекста или закрытия очереди.
+func (q *Queue) Start(ctx context.Context, workers int, handler MessageHandler) {
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg, ok := <-q.messages:
+					if !ok {
+						return
+					}
+					logger.Debug(fmt.Sprintf("[QUEUE] Message received, topic %s, offset: %d", q.topic, msg.offset))
+					if err := handler(ctx, msg.key, msg.value); err != nil {
+						logger.Error("Error processing queue message", "key", msg.key, "value", msg.value, "err", err)
+					}
+				}
+			}
+		}()
+	}
+	logger.Info("Start in-memory queue workers", "topic", q.topic, "workers", workers)
+}
+
+// SendMessage Постановка сообщения в очередь без ожидания. Партиция всегда 0, смещение - порядковый номер сообщения.
+func (q *Queue) SendMessage(key string, value string) (partition int32, offset int64, err error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return 0, 0, ErrQueueClosed
+	}
+
+	msg := message{key: key, value: value, offset: q.offset.Add(1)}
+	select {
+	case q.messages <- msg:
+		return 0, msg.offset, nil
+	default:
+		return 0, 0, ErrQueueFull
+	}
+}
+
+func (q *Queue) GetTopic() string {
+	return q.topic
+}
+
+// Close Закрытие очереди: новые сообщения не принимаются, обработчики завершают оставшиеся сообщения.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.messages)
+	}
+	q.mu.Unlock()
+
+	q.wg.Wait()
+	return nil
+}
@@ -0,0 +1,127 @@
+package nbrb
+
+// Клиент для получения официальных курсов валют Национального банка Республики Беларусь.
+// Документация API: https://www.nbrb.by/apihelp/exrates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+// DefaultBaseURL Адрес API НБРБ по умолчанию.
+const DefaultBaseURL = "https://api.nbrb.by"
+
+// baseCurrency Валюта, относительно которой НБРБ устанавливает курсы.
+const baseCurrency = "BYN"
+
+// nbrbRate Курс валюты в формате ответа API НБРБ.
+type nbrbRate struct {
+	CurID           int         `json:"Cur_ID"`
+	Date            string      `json:"Date"`
+	CurAbbreviation string      `json:"Cur_Abbreviation"`
+	CurScale        int         `json:"Cur_Scale"` // Количество единиц валюты, за которое установлен курс.
+	CurName         string      `json:"Cur_Name"`
+	CurOfficialRate json.Number `json:"Cur_OfficialRate"` // Стоимость Cur_Scale единиц валюты в BYN.
+}
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func New(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// GetExchangeRates Получение курсов валют на дату (нулевая дата - последние установленные курсы).
+// Курсы возвращаются относительно основной валюты: количество единиц валюты за 1 единицу основной.
+func (c *Client) GetExchangeRates(ctx context.Context, mainCurrency string, currencies []string, date time.Time) (bottypes.ExchangeRate, time.Time, error) {
+	url := c.baseURL + "/exrates/rates?periodicity=0"
+	if !date.IsZero() {
+		url += "&ondate=" + date.Format("2006-01-02")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("create nbrb request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("nbrb request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("nbrb request: unexpected status %s", resp.Status)
+	}
+
+	var nbrbRates []nbrbRate
+	if err := json.NewDecoder(resp.Body).Decode(&nbrbRates); err != nil {
+		return nil, time.Time{}, fmt.Errorf("decode nbrb response: %w", err)
+	}
+
+	return normalizeRates(nbrbRates, mainCurrency, currencies)
+}
+
+// normalizeRates Приведение курсов НБРБ к курсам относительно основной валюты.
+func normalizeRates(nbrbRates []nbrbRate, mainCurrency string, currencies []string) (bottypes.ExchangeRate, time.Time, error) {
+	// Количество единиц валюты за 1 BYN.
+	ratesBYN := bottypes.ExchangeRate{baseCurrency: money.OneRate}
+	var ratesDate time.Time
+
+	for _, r := range nbrbRates {
+		officialRate, ok := new(big.Rat).SetString(r.CurOfficialRate.String())
+		if !ok || officialRate.Sign() <= 0 || r.CurScale <= 0 {
+			logger.Warning("Skip incorrect nbrb rate", "currency", r.CurAbbreviation, "rate", r.CurOfficialRate, "scale", r.CurScale)
+			continue
+		}
+		rate, err := money.RateFromRat(new(big.Rat).Quo(big.NewRat(int64(r.CurScale), 1), officialRate))
+		if err != nil {
+			logger.Warning("Skip incorrect nbrb rate", "currency", r.CurAbbreviation, "err", err)
+			continue
+		}
+		ratesBYN[r.CurAbbreviation] = rate
+
+		if date, err := time.Parse("2006-01-02T15:04:05", r.Date); err == nil && date.After(ratesDate) {
+			ratesDate = date
+		}
+	}
+
+	mainRate, ok := ratesBYN[mainCurrency]
+	if !ok {
+		return nil, time.Time{}, fmt.Errorf("nbrb has no rate for main currency %s", mainCurrency)
+	}
+
+	rates := bottypes.ExchangeRate{}
+	for _, currency := range currencies {
+		rate, ok := ratesBYN[currency]
+		if !ok {
+			logger.Warning("Nbrb has no rate for currency", "currency", currency)
+			continue
+		}
+		rebased, err := rate.Div(mainRate)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("rebase nbrb rate %s: %w", currency, err)
+		}
+		rates[currency] = rebased
+	}
+	rates[mainCurrency] = money.OneRate
+
+	return rates, ratesDate, nil
+}
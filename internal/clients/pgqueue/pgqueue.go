@@ -0,0 +1,281 @@
+package pgqueue
+
+// Надежная очередь фоновых задач в таблице Postgres (см. migrations/0004_jobs.sql).
+// Обработчики забирают задачи через FOR UPDATE SKIP LOCKED и просыпаются по LISTEN/NOTIFY.
+// Неудачные задачи повторяются с экспоненциальной задержкой, после исчерпания попыток переводятся в статус dead.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/shoksin/financesBot/internal/helpers/dbutils"
+	"github.com/shoksin/financesBot/internal/logger"
+)
+
+// notifyChannel Канал уведомлений о новых задачах (в уведомлении передается имя очереди).
+const notifyChannel = "jobs"
+
+// Статусы задач.
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusDead    = "dead"
+)
+
+// MessageHandler Функция обработки задачи.
+type MessageHandler func(ctx context.Context, key string, value string) error
+
+// Options Настройки очереди.
+type Options struct {
+	MaxAttempts  int           // Количество попыток выполнения задачи.
+	BackoffBase  time.Duration // Задержка перед первой повторной попыткой (удваивается с каждой попыткой).
+	BackoffMax   time.Duration // Максимальная задержка перед повторной попыткой.
+	LockTimeout  time.Duration // Время, после которого задача в статусе running считается зависшей.
+	PollInterval time.Duration // Периодичность проверки задач без уведомлений (повторные попытки, пропущенные уведомления).
+}
+
+// DefaultOptions Настройки очереди по умолчанию.
+var DefaultOptions = Options{
+	MaxAttempts:  5,
+	BackoffBase:  10 * time.Second,
+	BackoffMax:   10 * time.Minute,
+	LockTimeout:  5 * time.Minute,
+	PollInterval: 30 * time.Second,
+}
+
+type job struct {
+	ID          int64  `db:"id"`
+	Key         string `db:"key"`
+	Payload     string `db:"payload"`
+	Attempts    int    `db:"attempts"`
+	MaxAttempts int    `db:"max_attempts"`
+}
+
+type Queue struct {
+	db         *sqlx.DB
+	connString string // Строка подключения для отдельного соединения LISTEN.
+	queue      string
+	opts       Options
+	wakeup     chan struct{}
+	wg         sync.WaitGroup
+	cancel     context.CancelFunc
+}
+
+func New(db *sqlx.DB, connString string, queue string, opts Options) *Queue {
+	return &Queue{
+		db:         db,
+		connString: connString,
+		queue:      queue,
+		opts:       opts,
+		wakeup:     make(chan struct{}, 1),
+		cancel:     func() {},
+	}
+}
+
+// Enqueue Добавление задачи в очередь и уведомление обработчиков.
+func (q *Queue) Enqueue(ctx context.Context, key string, payload string) (int64, error) {
+	const sqlString = `
+		INSERT INTO jobs (queue, key, payload, max_attempts)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id;`
+
+	var ids []int64
+	if err := dbutils.Select(ctx, q.db, &ids, sqlString, q.queue, key, payload, q.opts.MaxAttempts); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, errors.New("job is not saved")
+	}
+
+	if _, err := dbutils.Exec(ctx, q.db, `SELECT pg_notify($1, $2);`, notifyChannel, q.queue); err != nil {
+		// Задача сохранена и будет выполнена при периодической проверке.
+		logger.Warning("Error notifying job workers", "err", err)
+	}
+	return ids[0], nil
+}
+
+// SendMessage Реализация контракта продюсера Kafka: постановка задачи в очередь. Смещение - ID задачи.
+func (q *Queue) SendMessage(key string, value string) (partition int32, offset int64, err error) {
+	id, err := q.Enqueue(context.Background(), key, value)
+	return 0, id, err
+}
+
+func (q *Queue) GetTopic() string {
+	return q.queue
+}
+
+// Start Запуск обработчиков задач до отмены контекста или закрытия очереди.
+func (q *Queue) Start(ctx context.Context, workers int, handler MessageHandler) {
+	ctx, q.cancel = context.WithCancel(ctx)
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		q.listen(ctx)
+	}()
+
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			q.work(ctx, handler)
+		}()
+	}
+	logger.Info("Start postgres queue workers", "queue", q.queue, "workers", workers)
+}
+
+// Close Остановка обработчиков с ожиданием завершения выполняемых задач.
+func (q *Queue) Close() error {
+	q.cancel()
+	q.wg.Wait()
+	return nil
+}
+
+// listen Получение уведомлений о новых задачах через LISTEN с переподключением при ошибках.
+func (q *Queue) listen(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := q.listenConn(ctx); err != nil && ctx.Err() == nil {
+			logger.Error("Error listening job notifications", "err", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(q.opts.PollInterval):
+			}
+		}
+	}
+}
+
+func (q *Queue) listenConn(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, q.connString)
+	if err != nil {
+		return fmt.Errorf("connect for listen: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return fmt.Errorf("listen %s: %w", notifyChannel, err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+		if notification.Payload == q.queue {
+			q.wake()
+		}
+	}
+}
+
+func (q *Queue) wake() {
+	select {
+	case q.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// work Цикл обработчика: выполнение готовых задач, затем ожидание уведомления или периодической проверки.
+func (q *Queue) work(ctx context.Context, handler MessageHandler) {
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			processed, err := q.processNext(ctx, handler)
+			if err != nil {
+				logger.Error("Error processing job", "queue", q.queue, "err", err)
+				break
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wakeup:
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext Выполнение одной готовой задачи. Возвращает false, если готовых задач нет.
+func (q *Queue) processNext(ctx context.Context, handler MessageHandler) (bool, error) {
+	j, ok, err := q.claim(ctx)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	// Забранных задач может быть больше одной, будим следующий обработчик.
+	q.wake()
+
+	if errHandler := handler(ctx, j.Key, j.Payload); errHandler != nil {
+		logger.Error("Job failed", "queue", q.queue, "id", j.ID, "attempt", j.Attempts, "err", errHandler)
+		return true, q.fail(ctx, j, errHandler)
+	}
+	return true, q.complete(ctx, j)
+}
+
+// claim Захват готовой задачи (ожидающей или зависшей) с пропуском задач, заблокированных другими обработчиками.
+func (q *Queue) claim(ctx context.Context) (job, bool, error) {
+	const sqlString = `
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, locked_until = now() + $2 * INTERVAL '1 second', updated_at = now()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE queue = $1
+				AND ((status = 'pending' AND run_at <= now()) OR (status = 'running' AND locked_until < now()))
+			ORDER BY run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, key, payload, attempts, max_attempts;`
+
+	var jobs []job
+	if err := dbutils.Select(ctx, q.db, &jobs, sqlString, q.queue, q.opts.LockTimeout.Seconds()); err != nil {
+		return job{}, false, err
+	}
+	if len(jobs) == 0 {
+		return job{}, false, nil
+	}
+	return jobs[0], true, nil
+}
+
+func (q *Queue) complete(ctx context.Context, j job) error {
+	const sqlString = `UPDATE jobs SET status = 'done', locked_until = NULL, updated_at = now() WHERE id = $1;`
+
+	_, err := dbutils.Exec(ctx, q.db, sqlString, j.ID)
+	return err
+}
+
+// fail Возврат задачи в очередь с задержкой или перевод в статус dead после исчерпания попыток.
+func (q *Queue) fail(ctx context.Context, j job, errJob error) error {
+	const sqlString = `
+		UPDATE jobs
+		SET status = $2, run_at = now() + $3 * INTERVAL '1 second', locked_until = NULL, last_error = $4, updated_at = now()
+		WHERE id = $1;`
+
+	status := StatusPending
+	if j.Attempts >= j.MaxAttempts {
+		status = StatusDead
+		logger.Error("Job moved to dead state", "queue", q.queue, "id", j.ID, "attempts", j.Attempts)
+	}
+
+	_, err := dbutils.Exec(ctx, q.db, sqlString, j.ID, status, q.backoff(j.Attempts).Seconds(), errJob.Error())
+	return err
+}
+
+// backoff Задержка перед повторной попыткой: BackoffBase * 2^(attempts-1), не более BackoffMax.
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.opts.BackoffBase
+	for i := 1; i < attempts && delay < q.opts.BackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, q.opts.BackoffMax)
+}
@@ -2,10 +2,15 @@ package tg
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shoksin/financesBot/internal/helpers/tgfmt"
 	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
 	"github.com/shoksin/financesBot/internal/models/messages"
 )
 
@@ -15,9 +20,13 @@ func (f HandlerFunc) RunFunc(tgUpdate tgbotapi.Update, c *Client, msgModel *mess
 	f(tgUpdate, c, msgModel)
 }
 
+// maxDocumentSize Максимальный размер файла, принимаемого от пользователя.
+const maxDocumentSize = 5 << 20
+
 type Client struct {
 	client                *tgbotapi.BotAPI
-	handlerProcessingFunc HandlerFunc // Функция обработки входящих сообщений.
+	httpClient            *http.Client // Клиент для загрузки файлов, отправленных пользователями.
+	handlerProcessingFunc HandlerFunc  // Функция обработки входящих сообщений.
 }
 
 type TokenGetter interface {
@@ -32,13 +41,14 @@ func New(tokenGetter TokenGetter, handlerProcessingFunc HandlerFunc) (*Client, e
 
 	return &Client{
 		client:                client,
+		httpClient:            &http.Client{Timeout: 30 * time.Second},
 		handlerProcessingFunc: handlerProcessingFunc,
 	}, nil
 }
 
 func (c *Client) SendMessage(userID int64, text string) error {
 	msg := tgbotapi.NewMessage(userID, text) //Создаём конфиг сообщения
-	msg.ParseMode = "markdown"
+	msg.ParseMode = tgfmt.ParseMode
 	_, err := c.client.Send(msg) //отправляем сообщение в telegram
 	if err != nil {
 		return fmt.Errorf("error sending message client.Send: %v", err)
@@ -46,6 +56,49 @@ func (c *Client) SendMessage(userID int64, text string) error {
 	return nil
 }
 
+// SendPhoto Отправка изображения в формате PNG с подписью.
+func (c *Client) SendPhoto(userID int64, photo []byte, caption string) error {
+	msg := tgbotapi.NewPhoto(userID, tgbotapi.FileBytes{Name: "chart.png", Bytes: photo})
+	msg.Caption = caption
+	msg.ParseMode = tgfmt.ParseMode
+	_, err := c.client.Send(msg)
+	if err != nil {
+		return fmt.Errorf("error sending photo client.Send: %v", err)
+	}
+	return nil
+}
+
+// SendDocument Отправка файла с подписью.
+func (c *Client) SendDocument(userID int64, fileName string, data []byte, caption string) error {
+	msg := tgbotapi.NewDocument(userID, tgbotapi.FileBytes{Name: fileName, Bytes: data})
+	msg.Caption = caption
+	msg.ParseMode = tgfmt.ParseMode
+	_, err := c.client.Send(msg)
+	if err != nil {
+		return fmt.Errorf("error sending document client.Send: %v", err)
+	}
+	return nil
+}
+
+// ShowInlineButtons Отправка сообщения с inline-кнопками.
+func (c *Client) ShowInlineButtons(text string, buttons []bottypes.TgRowButtons, userID int64) error {
+	keyboard := make([][]tgbotapi.InlineKeyboardButton, len(buttons))
+	for i, row := range buttons {
+		for _, btn := range row {
+			keyboard[i] = append(keyboard[i], tgbotapi.NewInlineKeyboardButtonData(btn.DisplayName, btn.Value))
+		}
+	}
+
+	msg := tgbotapi.NewMessage(userID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	msg.ParseMode = tgfmt.ParseMode
+	_, err := c.client.Send(msg)
+	if err != nil {
+		return fmt.Errorf("error sending message with buttons client.Send: %v", err)
+	}
+	return nil
+}
+
 func (c *Client) ListenUpdates(msgModel *messages.Model) {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
@@ -66,14 +119,23 @@ func ProcessingMessages(tgUpdate tgbotapi.Update, c *Client, msgModel *messages.
 	if tgUpdate.Message != nil {
 		//Пользователь написал текстовое сообщение
 		logger.Info(fmt.Sprintf("[%s][%v] %s", tgUpdate.Message.From.UserName, tgUpdate.Message.From.ID, tgUpdate.Message.Text))
-		err := msgModel.IncomingMessage(messages.Message{
+		msg := messages.Message{
 			Text:            tgUpdate.Message.Text,
 			UserID:          tgUpdate.Message.From.ID,
 			UserName:        tgUpdate.Message.From.UserName,
-			UserDisplayName: strings.TrimSpace(tgUpdate.CallbackQuery.From.FirstName + " " + tgUpdate.CallbackQuery.From.LastName),
-			IsCallback:      true,
-			CallbackMsgID:   tgUpdate.CallbackQuery.ID,
-		})
+			UserDisplayName: strings.TrimSpace(tgUpdate.Message.From.FirstName + " " + tgUpdate.Message.From.LastName),
+		}
+		if tgUpdate.Message.Document != nil {
+			// Пользователь отправил файл (например, выписку банка).
+			msg.Text = tgUpdate.Message.Caption
+			msg.Document = &messages.Document{FileName: tgUpdate.Message.Document.FileName}
+			data, err := downloadDocument(c, tgUpdate.Message.Document)
+			if err != nil {
+				logger.Error("Error downloading document", "file", tgUpdate.Message.Document.FileName, "err", err)
+			}
+			msg.Document.Data = data
+		}
+		err := msgModel.IncomingMessage(msg)
 
 		if err != nil {
 			logger.Error("error processing message:", "err", err)
@@ -88,13 +150,49 @@ func ProcessingMessages(tgUpdate tgbotapi.Update, c *Client, msgModel *messages.
 		if err := deleteInlineButtons(c, tgUpdate.CallbackQuery.From.ID, tgUpdate.CallbackQuery.Message.MessageID, tgUpdate.CallbackQuery.Message.Text); err != nil {
 			logger.Error("Error deleting buttons", "err", err)
 		}
-		err := msgModel.IncomingMessage(messages.Message{})
+		err := msgModel.IncomingMessage(messages.Message{
+			Text:            tgUpdate.CallbackQuery.Data,
+			UserID:          tgUpdate.CallbackQuery.From.ID,
+			UserName:        tgUpdate.CallbackQuery.From.UserName,
+			UserDisplayName: strings.TrimSpace(tgUpdate.CallbackQuery.From.FirstName + " " + tgUpdate.CallbackQuery.From.LastName),
+			IsCallback:      true,
+			CallbackMsgID:   tgUpdate.CallbackQuery.ID,
+		})
 		if err != nil {
 			logger.Error("error processing message from callback:", "err", err)
 		}
 	}
 }
 
+// Загрузка содержимого файла, отправленного пользователем.
+func downloadDocument(c *Client, doc *tgbotapi.Document) ([]byte, error) {
+	if doc.FileSize > maxDocumentSize {
+		return nil, fmt.Errorf("document is too large: %d bytes", doc.FileSize)
+	}
+
+	url, err := c.client.GetFileDirectURL(doc.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("get file url: %w", err)
+	}
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download file: unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	if len(data) > maxDocumentSize {
+		return nil, fmt.Errorf("document is too large: more than %d bytes", maxDocumentSize)
+	}
+	return data, nil
+}
+
 func deleteInlineButtons(c *Client, userID int64, msgID int, sourceText string) error {
 	msg := tgbotapi.NewEditMessageText(userID, msgID, sourceText)
 	_, err := c.client.Send(msg)
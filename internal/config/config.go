@@ -17,8 +17,19 @@ type Config struct {
 	CurrenciesUpdatePeriod      int64    `yaml:"currencies_update_period"`       // Периодичность обновления курсов валют (в минутах).
 	CurrenciesUpdateCachePeriod int64    `yaml:"currencies_update_cache_period"` // Периодичность кэширования курсов валют из базы данных (в минутах).
 	ConnectionStringDB          string   `yaml:"connection_string_db"`
+	DefaultTimezone             string   `yaml:"default_timezone"` // Часовой пояс пользователей по умолчанию (имя из базы IANA).
 	KafkaTopic                  string   `yaml:"kafka_topic"`
-	BrokersList                 []string `yaml:"brokers_list"` // Список адресов брокеров сообщений (адрес Kafka).
+	BrokersList                 []string `yaml:"brokers_list"`      // Список адресов брокеров сообщений (адрес Kafka).
+	ReportQueue                 string   `yaml:"report_queue"`      // Очередь запросов на формирование отчетов: kafka, memory или postgres.
+	ReportQueueSize             int      `yaml:"report_queue_size"` // Размер очереди запросов в памяти.
+	ReportWorkers               *int     `yaml:"report_workers"`    // Количество обработчиков очереди запросов в процессе бота (0 - только report-service).
+	ReportTimeout               int64    `yaml:"report_timeout"`    // Время формирования отчета, после которого запрос считается зависшим (в минутах).
+	RatesProvider               string   `yaml:"rates_provider"`    // Источник курсов валют: nbrb или ecb.
+	NbrbURL                     string   `yaml:"nbrb_url"`          // Адрес API курсов валют НБРБ.
+	EcbURL                      string   `yaml:"ecb_url"`           // Адрес публикации курсов ЕЦБ.
+	RatesMaxAge                 int64    `yaml:"rates_max_age"`     // Возраст курсов валют, после которого они считаются устаревшими (в минутах).
+	ReportCacheSize             int      `yaml:"report_cache_size"` // Количество отчетов в кэше (кэш работает, только если отчеты формируются в процессе бота).
+	ReportCacheTTL              int64    `yaml:"report_cache_ttl"`  // Время жизни отчета в кэше (в минутах).
 }
 
 type Service struct {
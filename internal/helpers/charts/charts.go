@@ -0,0 +1,245 @@
+package charts
+
+// Построение PNG-диаграмм для отчетов средствами Go без внешних сервисов.
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	width      = 800
+	height     = 600
+	maxSlices  = 9  // Максимальное количество секторов круговой диаграммы (остальные объединяются в "Прочее").
+	maxXLabels = 12 // Максимальное количество подписей по оси X столбчатой диаграммы.
+)
+
+// Item Значение диаграммы.
+type Item struct {
+	Label string
+	Value float64
+}
+
+var (
+	palette = []color.RGBA{
+		{0x4e, 0x79, 0xa7, 0xff}, {0xf2, 0x8e, 0x2b, 0xff}, {0xe1, 0x57, 0x59, 0xff},
+		{0x76, 0xb7, 0xb2, 0xff}, {0x59, 0xa1, 0x4f, 0xff}, {0xed, 0xc9, 0x48, 0xff},
+		{0xb0, 0x7a, 0xa1, 0xff}, {0xff, 0x9d, 0xa7, 0xff}, {0x9c, 0x75, 0x5f, 0xff},
+		{0xba, 0xb0, 0xac, 0xff},
+	}
+	colorText = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	colorGrid = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+
+	facesOnce  sync.Once
+	titleFace  font.Face
+	labelFace  font.Face
+	errLoading error
+)
+
+// loadFaces Загрузка встроенного шрифта Go (поддерживает кириллицу).
+func loadFaces() error {
+	facesOnce.Do(func() {
+		f, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			errLoading = fmt.Errorf("parse chart font: %w", err)
+			return
+		}
+		if titleFace, err = opentype.NewFace(f, &opentype.FaceOptions{Size: 22, DPI: 72, Hinting: font.HintingFull}); err != nil {
+			errLoading = fmt.Errorf("create chart font face: %w", err)
+			return
+		}
+		if labelFace, err = opentype.NewFace(f, &opentype.FaceOptions{Size: 15, DPI: 72, Hinting: font.HintingFull}); err != nil {
+			errLoading = fmt.Errorf("create chart font face: %w", err)
+		}
+	})
+	return errLoading
+}
+
+// PieChart Круговая диаграмма (доли значений) с легендой.
+func PieChart(title string, items []Item) ([]byte, error) {
+	if err := loadFaces(); err != nil {
+		return nil, err
+	}
+
+	items = groupSmallItems(positiveItems(items))
+	total := 0.0
+	for _, item := range items {
+		total += item.Value
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("no data for chart")
+	}
+
+	img := newCanvas(title)
+
+	const cx, cy, r = 290, 330, 220
+	// Границы секторов в долях круга, начиная с верхней точки по часовой стрелке.
+	bounds := make([]float64, len(items))
+	acc := 0.0
+	for i, item := range items {
+		acc += item.Value / total
+		bounds[i] = acc
+	}
+
+	for y := cy - r; y <= cy+r; y++ {
+		for x := cx - r; x <= cx+r; x++ {
+			dx, dy := float64(x-cx), float64(y-cy)
+			if dx*dx+dy*dy > r*r {
+				continue
+			}
+			angle := math.Atan2(dx, -dy) / (2 * math.Pi)
+			if angle < 0 {
+				angle++
+			}
+			i := 0
+			for i < len(bounds)-1 && angle > bounds[i] {
+				i++
+			}
+			img.Set(x, y, palette[i%len(palette)])
+		}
+	}
+
+	// Легенда.
+	for i, item := range items {
+		y := 130 + i*34
+		fillRect(img, image.Rect(550, y-14, 568, y+4), palette[i%len(palette)])
+		drawText(img, labelFace, 576, y, fmt.Sprintf("%v — %.1f%%", item.Label, item.Value/total*100))
+	}
+
+	return encode(img)
+}
+
+// BarChart Столбчатая диаграмма значений по порядку (например, по дням или месяцам).
+func BarChart(title string, items []Item) ([]byte, error) {
+	if err := loadFaces(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no data for chart")
+	}
+
+	maxValue := 0.0
+	for _, item := range items {
+		maxValue = math.Max(maxValue, item.Value)
+	}
+	if maxValue <= 0 {
+		return nil, fmt.Errorf("no data for chart")
+	}
+	maxValue = niceCeil(maxValue)
+
+	img := newCanvas(title)
+
+	const left, right, top, bottom = 90, width - 30, 70, height - 70
+	plotHeight := float64(bottom - top)
+
+	// Сетка и подписи оси Y.
+	const yTicks = 5
+	for i := 0; i <= yTicks; i++ {
+		y := bottom - int(plotHeight*float64(i)/yTicks)
+		fillRect(img, image.Rect(left, y, right, y+1), colorGrid)
+		label := formatValue(maxValue * float64(i) / yTicks)
+		drawText(img, labelFace, left-10-font.MeasureString(labelFace, label).Round(), y+5, label)
+	}
+
+	step := float64(right-left) / float64(len(items))
+	barWidth := int(math.Max(1, step*0.7))
+	labelEvery := (len(items) + maxXLabels - 1) / maxXLabels
+	for i, item := range items {
+		x := left + int(step*float64(i)+(step-float64(barWidth))/2)
+		barHeight := int(plotHeight * math.Max(item.Value, 0) / maxValue)
+		fillRect(img, image.Rect(x, bottom-barHeight, x+barWidth, bottom), palette[0])
+
+		if i%labelEvery == 0 {
+			labelWidth := font.MeasureString(labelFace, item.Label).Round()
+			drawText(img, labelFace, x+barWidth/2-labelWidth/2, bottom+22, item.Label)
+		}
+	}
+
+	return encode(img)
+}
+
+func newCanvas(title string) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	titleWidth := font.MeasureString(titleFace, title).Round()
+	drawText(img, titleFace, (width-titleWidth)/2, 40, title)
+	return img
+}
+
+func drawText(img *image.RGBA, face font.Face, x, y int, text string) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(colorText),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+func encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encode chart: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func positiveItems(items []Item) []Item {
+	res := make([]Item, 0, len(items))
+	for _, item := range items {
+		if item.Value > 0 {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+// groupSmallItems Объединение значений сверх maxSlices в "Прочее" (значения отсортированы по убыванию).
+func groupSmallItems(items []Item) []Item {
+	if len(items) <= maxSlices {
+		return items
+	}
+	other := Item{Label: "Прочее"}
+	for _, item := range items[maxSlices-1:] {
+		other.Value += item.Value
+	}
+	return append(items[:maxSlices-1:maxSlices-1], other)
+}
+
+// niceCeil Округление максимума оси вверх до 1, 2 или 5 с множителем степени 10.
+func niceCeil(v float64) float64 {
+	exp := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*exp {
+			return m * exp
+		}
+	}
+	return 10 * exp
+}
+
+func formatValue(v float64) string {
+	switch {
+	case v >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case v >= 1e4:
+		return fmt.Sprintf("%.0fK", v/1e3)
+	case v == math.Trunc(v):
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
@@ -0,0 +1,153 @@
+package export
+
+// Выгрузка таблиц в файлы CSV и XLSX (минимальная книга Office Open XML с одним листом).
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Column Колонка таблицы.
+type Column struct {
+	Name    string
+	Numeric bool // Значения колонки записываются в XLSX как числа.
+}
+
+// Table Таблица для выгрузки.
+type Table struct {
+	Columns []Column
+	Rows    [][]string
+}
+
+// utf8BOM Метка порядка байт, чтобы Excel открывал CSV в кодировке UTF-8.
+const utf8BOM = "\ufeff"
+
+// CSV Выгрузка таблицы в CSV (RFC 4180, UTF-8 с BOM).
+func CSV(table Table) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(utf8BOM)
+
+	w := csv.NewWriter(&buf)
+	header := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		header[i] = col.Name
+	}
+	if err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("write csv header: %w", err)
+	}
+	if err := w.WriteAll(table.Rows); err != nil {
+		return nil, fmt.Errorf("write csv rows: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// XLSX Выгрузка таблицы в книгу XLSX с одним листом.
+func XLSX(table Table, sheetName string) ([]byte, error) {
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, escape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+		{"xl/worksheets/sheet1.xml", sheetXML(table)},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, fmt.Errorf("create xlsx part %s: %w", f.name, err)
+		}
+		if _, err := io.WriteString(w, f.content); err != nil {
+			return nil, fmt.Errorf("write xlsx part %s: %w", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close xlsx: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// sheetXML Содержимое листа: первая строка - заголовки (жирный шрифт), далее строки таблицы.
+func sheetXML(table Table) string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	sb.WriteString(`<row r="1">`)
+	for i, col := range table.Columns {
+		fmt.Fprintf(&sb, `<c r="%s1" t="inlineStr" s="1"><is><t>%s</t></is></c>`, columnName(i), escape(col.Name))
+	}
+	sb.WriteString(`</row>`)
+
+	for r, row := range table.Rows {
+		fmt.Fprintf(&sb, `<row r="%d">`, r+2)
+		for i, value := range row {
+			ref := fmt.Sprintf("%s%d", columnName(i), r+2)
+			if i < len(table.Columns) && table.Columns[i].Numeric && value != "" {
+				fmt.Fprintf(&sb, `<c r="%s"><v>%s</v></c>`, ref, escape(value))
+				continue
+			}
+			fmt.Fprintf(&sb, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escape(value))
+		}
+		sb.WriteString(`</row>`)
+	}
+
+	sb.WriteString(`</sheetData></worksheet>`)
+	return sb.String()
+}
+
+// columnName Имя колонки листа по индексу: 0 - A, 25 - Z, 26 - AA.
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// xlsxStyles Стили: 0 - обычный, 1 - жирный шрифт для заголовков.
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`</styleSheet>`
@@ -0,0 +1,209 @@
+package lrucache
+
+// Кэш с вытеснением давно не используемых записей (LRU), ограничением размера и временем жизни записей.
+// Записи группируются по пользователю, чтобы при изменении данных пользователя удалять все его записи.
+// Поколение кэша увеличивается при каждом удалении записей пользователя: запись, вычисленная по данным,
+// прочитанным до удаления, не добавляется в кэш. Поколения удаления хранятся для capacity последних
+// пользователей, для остальных запись отклоняется, если она вычислена до последнего вытесненного удаления.
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Метрики.
+var (
+	cacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tg",
+		Subsystem: "cache",
+		Name:      "hits_total", // Количество найденных в кэше записей.
+	}, []string{"cache"})
+	cacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tg",
+		Subsystem: "cache",
+		Name:      "misses_total", // Количество не найденных или просроченных записей.
+	}, []string{"cache"})
+	cacheEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tg",
+		Subsystem: "cache",
+		Name:      "evictions_total", // Количество записей, вытесненных из-за ограничения размера.
+	}, []string{"cache"})
+)
+
+type entryKey struct {
+	userID int64
+	key    string
+}
+
+type entry struct {
+	key       entryKey
+	value     any
+	expiresAt time.Time
+}
+
+// removal Последнее удаление записей пользователя.
+type removal struct {
+	userID     int64
+	generation uint64
+}
+
+// LRUCache Потокобезопасный кэш ограниченного размера.
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	items    map[entryKey]*list.Element
+	users    map[int64]map[string]*list.Element // Записи пользователя для удаления по пользователю.
+	order    *list.List                         // Записи от недавно использованных к давно не используемым.
+	gen      uint64                             // Текущее поколение кэша.
+	minGen   uint64                             // Поколение последнего вытесненного удаления.
+	removals map[int64]*list.Element            // Последние удаления записей по пользователю.
+	remOrder *list.List                         // Удаления от давних к последним.
+	hits     prometheus.Counter
+	misses   prometheus.Counter
+	evicts   prometheus.Counter
+	now      func() time.Time
+}
+
+// New Кэш на capacity записей со временем жизни записей ttl. Метрики кэша помечаются названием name.
+func New(name string, capacity int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		capacity: max(capacity, 1),
+		ttl:      ttl,
+		items:    map[entryKey]*list.Element{},
+		users:    map[int64]map[string]*list.Element{},
+		removals: map[int64]*list.Element{},
+		remOrder: list.New(),
+		order:    list.New(),
+		hits:     cacheHits.WithLabelValues(name),
+		misses:   cacheMisses.WithLabelValues(name),
+		evicts:   cacheEvictions.WithLabelValues(name),
+		now:      time.Now,
+	}
+}
+
+// Generation Текущее поколение кэша. Получается до чтения данных, из которых вычисляется запись.
+func (c *LRUCache) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.gen
+}
+
+// Add Добавление записи пользователя со временем жизни по умолчанию.
+// Запись не добавляется, если после получения поколения generation (см. Generation) записи пользователя удалялись.
+func (c *LRUCache) Add(userID int64, generation uint64, key string, value any) {
+	c.AddWithTTL(userID, generation, key, value, c.ttl)
+}
+
+// AddWithTTL Добавление записи пользователя со временем жизни ttl.
+// При превышении размера удаляется давно не используемая запись.
+func (c *LRUCache) AddWithTTL(userID int64, generation uint64, key string, value any, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation < c.minGen {
+		return
+	}
+	if elem, ok := c.removals[userID]; ok && elem.Value.(*removal).generation > generation {
+		return
+	}
+
+	k := entryKey{userID: userID, key: key}
+	e := &entry{key: k, value: value, expiresAt: c.now().Add(ttl)}
+	if elem, ok := c.items[k]; ok {
+		elem.Value = e
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	elem := c.order.PushFront(e)
+	c.items[k] = elem
+	if c.users[userID] == nil {
+		c.users[userID] = map[string]*list.Element{}
+	}
+	c.users[userID][key] = elem
+
+	for c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+		c.evicts.Inc()
+	}
+}
+
+// Get Получение записи пользователя (nil, если записи нет или время ее жизни истекло).
+func (c *LRUCache) Get(userID int64, key string) any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[entryKey{userID: userID, key: key}]
+	if !ok {
+		c.misses.Inc()
+		return nil
+	}
+	e := elem.Value.(*entry)
+	if !c.now().Before(e.expiresAt) {
+		c.removeElement(elem)
+		c.misses.Inc()
+		return nil
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits.Inc()
+	return e.value
+}
+
+// Remove Удаление записи пользователя.
+func (c *LRUCache) Remove(userID int64, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[entryKey{userID: userID, key: key}]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// RemoveUser Удаление всех записей пользователя и переход к следующему поколению кэша.
+func (c *LRUCache) RemoveUser(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	if elem, ok := c.removals[userID]; ok {
+		elem.Value.(*removal).generation = c.gen
+		c.remOrder.MoveToBack(elem)
+	} else {
+		c.removals[userID] = c.remOrder.PushBack(&removal{userID: userID, generation: c.gen})
+	}
+	for c.remOrder.Len() > c.capacity {
+		r := c.remOrder.Remove(c.remOrder.Front()).(*removal)
+		delete(c.removals, r.userID)
+		c.minGen = r.generation
+	}
+	for _, elem := range c.users[userID] {
+		c.removeElement(elem)
+	}
+}
+
+// Len Количество записей в кэше (включая просроченные, которые еще не удалены).
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRUCache) removeElement(elem *list.Element) {
+	e := elem.Value.(*entry)
+	c.order.Remove(elem)
+	delete(c.items, e.key)
+	if userItems := c.users[e.key.userID]; userItems != nil {
+		delete(userItems, e.key.key)
+		if len(userItems) == 0 {
+			delete(c.users, e.key.userID)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package money
+
+// Денежные суммы с фиксированной точностью.
+// Сумма хранится целым числом минимальных единиц валюты (копейки, центы) согласно ISO 4217.
+// Правило округления: до минимальной единицы валюты, половина округляется от нуля (1.005 -> 1.01, -1.005 -> -1.01).
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// defaultMinorUnits Количество знаков минимальных единиц для валют, отсутствующих в справочнике.
+const defaultMinorUnits = 2
+
+// minorUnits Количество знаков после запятой для валют (ISO 4217), отличающееся от двух.
+var minorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// MinorUnits Количество знаков после запятой в сумме валюты.
+func MinorUnits(currency string) int {
+	if digits, ok := minorUnits[currency]; ok {
+		return digits
+	}
+	return defaultMinorUnits
+}
+
+// Money Денежная сумма в валюте.
+type Money struct {
+	amount   int64 // Сумма в минимальных единицах валюты.
+	currency string
+}
+
+// New Создание суммы из количества минимальных единиц валюты.
+func New(amount int64, currency string) Money {
+	return Money{amount: amount, currency: currency}
+}
+
+// Zero Нулевая сумма в валюте.
+func Zero(currency string) Money {
+	return Money{currency: currency}
+}
+
+// Parse Разбор десятичной записи суммы ("350", "350.5", "350,50").
+// Лишние знаки после запятой округляются по правилу пакета.
+func Parse(s string, currency string) (Money, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/eE") {
+		return Money{}, fmt.Errorf("incorrect sum %q", s)
+	}
+	return FromRat(r, currency)
+}
+
+// FromRat Создание суммы из рационального числа с округлением до минимальной единицы валюты.
+func FromRat(r *big.Rat, currency string) (Money, error) {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(MinorUnits(currency))))
+	amount := roundRat(scaled)
+	if !amount.IsInt64() {
+		return Money{}, fmt.Errorf("sum %s is out of range", r.FloatString(MinorUnits(currency)))
+	}
+	return Money{amount: amount.Int64(), currency: currency}, nil
+}
+
+// Amount Сумма в минимальных единицах валюты.
+func (m Money) Amount() int64 {
+	return m.amount
+}
+
+func (m Money) Currency() string {
+	return m.currency
+}
+
+// Rat Сумма в виде рационального числа (в основных единицах валюты).
+func (m Money) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(m.amount), pow10(MinorUnits(m.currency)))
+}
+
+// String Десятичная запись суммы без валюты ("350.50").
+func (m Money) String() string {
+	return m.Rat().FloatString(MinorUnits(m.currency))
+}
+
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.amount < 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.amount > 0
+}
+
+// Add Сложение сумм в одной валюте.
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{amount: m.amount + other.amount, currency: m.currency}, nil
+}
+
+// Sub Вычитание сумм в одной валюте.
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{amount: m.amount - other.amount, currency: m.currency}, nil
+}
+
+// Cmp Сравнение сумм в одной валюте (-1, 0, 1).
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.amount < other.amount:
+		return -1, nil
+	case m.amount > other.amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Convert Пересчет суммы в другую валюту умножением на курс (количество единиц валюты to за 1 единицу текущей).
+func (m Money) Convert(rate Rate, to string) (Money, error) {
+	return FromRat(new(big.Rat).Mul(m.Rat(), rate.Rat()), to)
+}
+
+// ConvertInverse Пересчет суммы в другую валюту делением на курс (количество единиц текущей валюты за 1 единицу to).
+func (m Money) ConvertInverse(rate Rate, to string) (Money, error) {
+	if !rate.IsPositive() {
+		return Money{}, fmt.Errorf("incorrect rate %s", rate)
+	}
+	return FromRat(new(big.Rat).Quo(m.Rat(), rate.Rat()), to)
+}
+
+func (m Money) checkCurrency(other Money) error {
+	if m.currency != other.currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, other.currency)
+	}
+	return nil
+}
+
+// roundRat Округление до целого, половина округляется от нуля.
+func roundRat(r *big.Rat) *big.Int {
+	num := new(big.Int).Abs(r.Num())
+	den := r.Denom()
+
+	// (2*|num| + den) / (2*den)
+	q := new(big.Int).Mul(num, big.NewInt(2))
+	q.Add(q, den)
+	q.Quo(q, new(big.Int).Mul(den, big.NewInt(2)))
+
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,75 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RateDigits Количество знаков после запятой, с которым хранятся курсы валют.
+const RateDigits = 12
+
+// Rate Курс валюты с фиксированной точностью (RateDigits знаков после запятой).
+type Rate struct {
+	units int64 // Курс, умноженный на 10^RateDigits.
+}
+
+// OneRate Курс валюты к самой себе.
+var OneRate = Rate{units: pow10(RateDigits).Int64()}
+
+// ParseRate Разбор десятичной записи курса ("3.2719").
+func ParseRate(s string) (Rate, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/eE") {
+		return Rate{}, fmt.Errorf("incorrect rate %q", s)
+	}
+	return RateFromRat(r)
+}
+
+// RateFromRat Создание курса из рационального числа с округлением до RateDigits знаков.
+func RateFromRat(r *big.Rat) (Rate, error) {
+	units := roundRat(new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(RateDigits))))
+	if !units.IsInt64() {
+		return Rate{}, fmt.Errorf("rate %s is out of range", r.FloatString(RateDigits))
+	}
+	return Rate{units: units.Int64()}, nil
+}
+
+// Rat Курс в виде рационального числа.
+func (r Rate) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(r.units), pow10(RateDigits))
+}
+
+// String Десятичная запись курса без незначащих нулей.
+func (r Rate) String() string {
+	s := strings.TrimRight(r.Rat().FloatString(RateDigits), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// FloatString Десятичная запись курса с заданным количеством знаков после запятой.
+func (r Rate) FloatString(prec int) string {
+	return r.Rat().FloatString(prec)
+}
+
+func (r Rate) IsPositive() bool {
+	return r.units > 0
+}
+
+// IsZero Проверка, что курс равен нулю (например, после округления очень маленького кросс-курса).
+func (r Rate) IsZero() bool {
+	return r.units == 0
+}
+
+// Div Отношение курсов (используется для пересчета курсов к другой базовой валюте).
+func (r Rate) Div(other Rate) (Rate, error) {
+	if !other.IsPositive() {
+		return Rate{}, fmt.Errorf("incorrect rate %s", other)
+	}
+	return RateFromRat(new(big.Rat).Quo(r.Rat(), other.Rat()))
+}
+
+// Inverse Обратный курс.
+func (r Rate) Inverse() (Rate, error) {
+	return OneRate.Div(r)
+}
@@ -0,0 +1,61 @@
+package tgfmt
+
+// Форматирование сообщений Telegram в режиме HTML.
+// Текст пользователя (названия категорий, комментарии, имена) экранируется, поэтому символы
+// разметки в нем выводятся как есть и не ломают сообщение.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseMode Режим разметки сообщений, сформированных функциями пакета.
+const ParseMode = "HTML"
+
+// HTML Текст с разметкой, который не нужно экранировать повторно.
+type HTML string
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Escape Экранирование текста для вывода в сообщении как есть.
+func Escape(text string) string {
+	return escaper.Replace(text)
+}
+
+// Bold Текст жирным шрифтом.
+func Bold(text string) HTML {
+	return HTML("<b>" + Escape(text) + "</b>")
+}
+
+// Italic Текст курсивом.
+func Italic(text string) HTML {
+	return HTML("<i>" + Escape(text) + "</i>")
+}
+
+// Code Текст моноширинным шрифтом.
+func Code(text string) HTML {
+	return HTML("<code>" + Escape(text) + "</code>")
+}
+
+// Sprintf Подстановка аргументов в шаблон с разметкой.
+// Строки, ошибки и значения с методом String экранируются, значения типа HTML и числа подставляются как есть.
+// Ширина строковых аргументов (%*s) учитывает символы экранирования, поэтому строки таблиц выравниваются
+// через fmt.Sprintf и выводятся целиком через Code.
+func Sprintf(format string, args ...any) string {
+	escaped := make([]any, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case HTML:
+			escaped[i] = string(v)
+		case string:
+			escaped[i] = Escape(v)
+		case error:
+			escaped[i] = Escape(v.Error())
+		case fmt.Stringer:
+			escaped[i] = Escape(v.String())
+		default:
+			escaped[i] = arg
+		}
+	}
+	return fmt.Sprintf(format, escaped...)
+}
@@ -0,0 +1,176 @@
+package timeutils
+
+import (
+	"fmt"
+	"time"
+)
+
+// Unit Единица календарного периода.
+type Unit int
+
+const (
+	Custom Unit = iota // Произвольный диапазон.
+	Day
+	Week
+	Month
+	Quarter
+	Year
+)
+
+func (u Unit) String() string {
+	switch u {
+	case Day:
+		return "day"
+	case Week:
+		return "week"
+	case Month:
+		return "month"
+	case Quarter:
+		return "quarter"
+	case Year:
+		return "year"
+	}
+	return "custom"
+}
+
+// Period Календарный период [Start, End) в часовом поясе начала периода.
+type Period struct {
+	unit  Unit
+	start time.Time
+	end   time.Time
+}
+
+// NewPeriod Календарный период, содержащий момент t, в часовом поясе loc (nil - UTC).
+// Для недели используется начало недели с понедельника (ISO 8601), см. NewWeek.
+func NewPeriod(unit Unit, t time.Time, loc *time.Location) (Period, error) {
+	switch unit {
+	case Day, Month, Quarter, Year:
+		return newPeriod(unit, t, loc, time.Monday), nil
+	case Week:
+		return NewWeek(t, loc, time.Monday), nil
+	}
+	return Period{}, fmt.Errorf("period unit %s requires explicit bounds", unit)
+}
+
+// NewDay День, содержащий момент t.
+func NewDay(t time.Time, loc *time.Location) Period {
+	return newPeriod(Day, t, loc, time.Monday)
+}
+
+// NewWeek Неделя, содержащая момент t, с началом недели в день weekStart (time.Monday - ISO 8601, time.Sunday).
+func NewWeek(t time.Time, loc *time.Location, weekStart time.Weekday) Period {
+	return newPeriod(Week, t, loc, weekStart)
+}
+
+// NewMonth Месяц, содержащий момент t.
+func NewMonth(t time.Time, loc *time.Location) Period {
+	return newPeriod(Month, t, loc, time.Monday)
+}
+
+// NewQuarter Квартал, содержащий момент t.
+func NewQuarter(t time.Time, loc *time.Location) Period {
+	return newPeriod(Quarter, t, loc, time.Monday)
+}
+
+// NewYear Год, содержащий момент t.
+func NewYear(t time.Time, loc *time.Location) Period {
+	return newPeriod(Year, t, loc, time.Monday)
+}
+
+// NewRange Произвольный период [start, end).
+func NewRange(start time.Time, end time.Time) (Period, error) {
+	if end.Before(start) {
+		return Period{}, fmt.Errorf("period end %s is before start %s", end, start)
+	}
+	return Period{unit: Custom, start: start, end: end}, nil
+}
+
+func newPeriod(unit Unit, t time.Time, loc *time.Location, weekStart time.Weekday) Period {
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+
+	p := Period{unit: unit}
+	switch unit {
+	case Day:
+		p.start = day
+	case Week:
+		p.start = day.AddDate(0, 0, -((int(day.Weekday()) - int(weekStart) + 7) % 7))
+	case Month:
+		p.start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	case Quarter:
+		p.start = time.Date(t.Year(), (t.Month()-1)/3*3+1, 1, 0, 0, 0, 0, loc)
+	case Year:
+		p.start = time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, loc)
+	}
+	p.end = p.shift(p.start, 1)
+	return p
+}
+
+// shift Сдвиг момента на n периодов. Календарные единицы сдвигаются через AddDate (с учетом перехода на летнее время).
+func (p Period) shift(t time.Time, n int) time.Time {
+	switch p.unit {
+	case Day:
+		return t.AddDate(0, 0, n)
+	case Week:
+		return t.AddDate(0, 0, 7*n)
+	case Month:
+		return t.AddDate(0, n, 0)
+	case Quarter:
+		return t.AddDate(0, 3*n, 0)
+	case Year:
+		return t.AddDate(n, 0, 0)
+	}
+	return t.Add(time.Duration(n) * p.end.Sub(p.start))
+}
+
+// Unit Единица периода.
+func (p Period) Unit() Unit {
+	return p.unit
+}
+
+// Start Начало периода (включается).
+func (p Period) Start() time.Time {
+	return p.start
+}
+
+// End Конец периода (не включается).
+func (p Period) End() time.Time {
+	return p.end
+}
+
+// Last Последний день периода (для вывода диапазона дат).
+func (p Period) Last() time.Time {
+	last := p.end.AddDate(0, 0, -1)
+	if last.Before(p.start) {
+		return p.start
+	}
+	return last
+}
+
+// Next Следующий период той же длины.
+func (p Period) Next() Period {
+	next := p
+	next.start = p.end
+	next.end = p.shift(p.end, 1)
+	return next
+}
+
+// Prev Предыдущий период той же длины.
+func (p Period) Prev() Period {
+	prev := p
+	prev.end = p.start
+	prev.start = p.shift(p.start, -1)
+	return prev
+}
+
+// Contains Проверка, что момент t входит в период.
+func (p Period) Contains(t time.Time) bool {
+	return !t.Before(p.start) && t.Before(p.end)
+}
+
+func (p Period) String() string {
+	return fmt.Sprintf("%s [%s, %s)", p.unit, p.start.Format(time.RFC3339), p.end.Format(time.RFC3339))
+}
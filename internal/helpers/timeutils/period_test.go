@@ -0,0 +1,423 @@
+package timeutils
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // Часовые пояса для тестов на системах без tzdata.
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func mustParse(t *testing.T, value string) time.Time {
+	t.Helper()
+	res, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("parse time %s: %v", value, err)
+	}
+	return res
+}
+
+func mustRange(t *testing.T, start, end string) Period {
+	t.Helper()
+	p, err := NewRange(mustParse(t, start), mustParse(t, end))
+	if err != nil {
+		t.Fatalf("new range: %v", err)
+	}
+	return p
+}
+
+func checkBounds(t *testing.T, p Period, wantStart, wantEnd string) {
+	t.Helper()
+	if start := mustParse(t, wantStart); !p.Start().Equal(start) || p.Start().Format(time.RFC3339) != wantStart {
+		t.Errorf("Start() = %s, want %s", p.Start().Format(time.RFC3339), wantStart)
+	}
+	if end := mustParse(t, wantEnd); !p.End().Equal(end) || p.End().Format(time.RFC3339) != wantEnd {
+		t.Errorf("End() = %s, want %s", p.End().Format(time.RFC3339), wantEnd)
+	}
+}
+
+func TestNewPeriod(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	minsk := mustLoadLocation(t, "Europe/Minsk")
+
+	tests := []struct {
+		name      string
+		period    func(t *testing.T) Period
+		wantUnit  Unit
+		wantStart string
+		wantEnd   string
+	}{
+		{
+			name:      "day",
+			period:    func(t *testing.T) Period { return NewDay(mustParse(t, "2024-03-15T13:45:00Z"), time.UTC) },
+			wantUnit:  Day,
+			wantStart: "2024-03-15T00:00:00Z",
+			wantEnd:   "2024-03-16T00:00:00Z",
+		},
+		{
+			name:      "day without location is UTC",
+			period:    func(t *testing.T) Period { return NewDay(mustParse(t, "2024-03-15T23:30:00+03:00"), nil) },
+			wantUnit:  Day,
+			wantStart: "2024-03-15T00:00:00Z",
+			wantEnd:   "2024-03-16T00:00:00Z",
+		},
+		{
+			name:      "week from monday",
+			period:    func(t *testing.T) Period { return NewWeek(mustParse(t, "2024-03-14T10:00:00Z"), time.UTC, time.Monday) },
+			wantUnit:  Week,
+			wantStart: "2024-03-11T00:00:00Z",
+			wantEnd:   "2024-03-18T00:00:00Z",
+		},
+		{
+			name:      "week from monday on sunday",
+			period:    func(t *testing.T) Period { return NewWeek(mustParse(t, "2024-03-17T10:00:00Z"), time.UTC, time.Monday) },
+			wantUnit:  Week,
+			wantStart: "2024-03-11T00:00:00Z",
+			wantEnd:   "2024-03-18T00:00:00Z",
+		},
+		{
+			name:      "week from sunday",
+			period:    func(t *testing.T) Period { return NewWeek(mustParse(t, "2024-03-14T10:00:00Z"), time.UTC, time.Sunday) },
+			wantUnit:  Week,
+			wantStart: "2024-03-10T00:00:00Z",
+			wantEnd:   "2024-03-17T00:00:00Z",
+		},
+		{
+			name:      "week from sunday on sunday",
+			period:    func(t *testing.T) Period { return NewWeek(mustParse(t, "2024-03-17T10:00:00Z"), time.UTC, time.Sunday) },
+			wantUnit:  Week,
+			wantStart: "2024-03-17T00:00:00Z",
+			wantEnd:   "2024-03-24T00:00:00Z",
+		},
+		{
+			name: "week via NewPeriod starts on monday",
+			period: func(t *testing.T) Period {
+				p, err := NewPeriod(Week, mustParse(t, "2024-01-01T00:00:00Z"), time.UTC)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return p
+			},
+			wantUnit:  Week,
+			wantStart: "2024-01-01T00:00:00Z",
+			wantEnd:   "2024-01-08T00:00:00Z",
+		},
+		{
+			name:      "month in leap year",
+			period:    func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-02-10T12:00:00Z"), time.UTC) },
+			wantUnit:  Month,
+			wantStart: "2024-02-01T00:00:00Z",
+			wantEnd:   "2024-03-01T00:00:00Z",
+		},
+		{
+			name:      "quarter",
+			period:    func(t *testing.T) Period { return NewQuarter(mustParse(t, "2024-05-20T12:00:00Z"), time.UTC) },
+			wantUnit:  Quarter,
+			wantStart: "2024-04-01T00:00:00Z",
+			wantEnd:   "2024-07-01T00:00:00Z",
+		},
+		{
+			name:      "last quarter of year",
+			period:    func(t *testing.T) Period { return NewQuarter(mustParse(t, "2024-11-30T12:00:00Z"), time.UTC) },
+			wantUnit:  Quarter,
+			wantStart: "2024-10-01T00:00:00Z",
+			wantEnd:   "2025-01-01T00:00:00Z",
+		},
+		{
+			name:      "year",
+			period:    func(t *testing.T) Period { return NewYear(mustParse(t, "2024-07-01T12:00:00Z"), time.UTC) },
+			wantUnit:  Year,
+			wantStart: "2024-01-01T00:00:00Z",
+			wantEnd:   "2025-01-01T00:00:00Z",
+		},
+		{
+			name:      "custom range",
+			period:    func(t *testing.T) Period { return mustRange(t, "2024-01-10T00:00:00Z", "2024-01-20T00:00:00Z") },
+			wantUnit:  Custom,
+			wantStart: "2024-01-10T00:00:00Z",
+			wantEnd:   "2024-01-20T00:00:00Z",
+		},
+		{
+			name:      "day with DST start in Berlin",
+			period:    func(t *testing.T) Period { return NewDay(mustParse(t, "2024-03-31T12:00:00+02:00"), berlin) },
+			wantUnit:  Day,
+			wantStart: "2024-03-31T00:00:00+01:00",
+			wantEnd:   "2024-04-01T00:00:00+02:00",
+		},
+		{
+			name: "week with DST start in Berlin",
+			period: func(t *testing.T) Period {
+				return NewWeek(mustParse(t, "2024-03-27T12:00:00+01:00"), berlin, time.Monday)
+			},
+			wantUnit:  Week,
+			wantStart: "2024-03-25T00:00:00+01:00",
+			wantEnd:   "2024-04-01T00:00:00+02:00",
+		},
+		{
+			name:      "month with DST start in Berlin",
+			period:    func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-03-31T03:30:00+02:00"), berlin) },
+			wantUnit:  Month,
+			wantStart: "2024-03-01T00:00:00+01:00",
+			wantEnd:   "2024-04-01T00:00:00+02:00",
+		},
+		{
+			// 01:00 1 марта в Минске - еще 29 февраля по UTC.
+			name:      "month in Minsk at 01:00 on the 1st",
+			period:    func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-02-29T22:00:00Z"), minsk) },
+			wantUnit:  Month,
+			wantStart: "2024-03-01T00:00:00+03:00",
+			wantEnd:   "2024-04-01T00:00:00+03:00",
+		},
+		{
+			name:      "day in Minsk at 01:00 on the 1st",
+			period:    func(t *testing.T) Period { return NewDay(mustParse(t, "2024-02-29T22:00:00Z"), minsk) },
+			wantUnit:  Day,
+			wantStart: "2024-03-01T00:00:00+03:00",
+			wantEnd:   "2024-03-02T00:00:00+03:00",
+		},
+		{
+			name:      "year in Minsk at 01:00 on January 1st",
+			period:    func(t *testing.T) Period { return NewYear(mustParse(t, "2024-12-31T22:00:00Z"), minsk) },
+			wantUnit:  Year,
+			wantStart: "2025-01-01T00:00:00+03:00",
+			wantEnd:   "2026-01-01T00:00:00+03:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.period(t)
+			if p.Unit() != tt.wantUnit {
+				t.Errorf("Unit() = %s, want %s", p.Unit(), tt.wantUnit)
+			}
+			checkBounds(t, p, tt.wantStart, tt.wantEnd)
+		})
+	}
+}
+
+func TestNewPeriodErrors(t *testing.T) {
+	if _, err := NewPeriod(Custom, time.Now(), time.UTC); err == nil {
+		t.Error("NewPeriod(Custom) error = nil, want error")
+	}
+	if _, err := NewRange(mustParse(t, "2024-01-20T00:00:00Z"), mustParse(t, "2024-01-10T00:00:00Z")); err == nil {
+		t.Error("NewRange(end before start) error = nil, want error")
+	}
+}
+
+func TestPeriodLast(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name   string
+		period func(t *testing.T) Period
+		want   string
+	}{
+		{
+			name:   "day",
+			period: func(t *testing.T) Period { return NewDay(mustParse(t, "2024-03-15T13:45:00Z"), time.UTC) },
+			want:   "2024-03-15T00:00:00Z",
+		},
+		{
+			name:   "month in leap year",
+			period: func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-02-10T12:00:00Z"), time.UTC) },
+			want:   "2024-02-29T00:00:00Z",
+		},
+		{
+			name:   "quarter",
+			period: func(t *testing.T) Period { return NewQuarter(mustParse(t, "2024-11-30T12:00:00Z"), time.UTC) },
+			want:   "2024-12-31T00:00:00Z",
+		},
+		{
+			name:   "month with DST start in Berlin",
+			period: func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-03-15T12:00:00+01:00"), berlin) },
+			want:   "2024-03-31T00:00:00+01:00",
+		},
+		{
+			name:   "empty custom range",
+			period: func(t *testing.T) Period { return mustRange(t, "2024-01-10T12:00:00Z", "2024-01-10T12:00:00Z") },
+			want:   "2024-01-10T12:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.period(t).Last().Format(time.RFC3339); got != tt.want {
+				t.Errorf("Last() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeriodContains(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	minsk := mustLoadLocation(t, "Europe/Minsk")
+
+	tests := []struct {
+		name   string
+		period func(t *testing.T) Period
+		at     string
+		want   bool
+	}{
+		{
+			name:   "start is included",
+			period: func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-03-15T00:00:00Z"), time.UTC) },
+			at:     "2024-03-01T00:00:00Z",
+			want:   true,
+		},
+		{
+			name:   "end is excluded",
+			period: func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-03-15T00:00:00Z"), time.UTC) },
+			at:     "2024-04-01T00:00:00Z",
+			want:   false,
+		},
+		{
+			name:   "moment before end",
+			period: func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-03-15T00:00:00Z"), time.UTC) },
+			at:     "2024-03-31T23:59:59Z",
+			want:   true,
+		},
+		{
+			name:   "moment before start",
+			period: func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-03-15T00:00:00Z"), time.UTC) },
+			at:     "2024-02-29T23:59:59Z",
+			want:   false,
+		},
+		{
+			name:   "last hour of day with DST start in Berlin",
+			period: func(t *testing.T) Period { return NewDay(mustParse(t, "2024-03-31T12:00:00+02:00"), berlin) },
+			at:     "2024-03-31T23:30:00+02:00",
+			want:   true,
+		},
+		{
+			name:   "UTC moment of previous day in Minsk month",
+			period: func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-03-15T12:00:00+03:00"), minsk) },
+			at:     "2024-02-29T22:00:00Z",
+			want:   true,
+		},
+		{
+			name:   "UTC moment before Minsk month",
+			period: func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-03-15T12:00:00+03:00"), minsk) },
+			at:     "2024-02-29T20:59:59Z",
+			want:   false,
+		},
+		{
+			name:   "custom range end is excluded",
+			period: func(t *testing.T) Period { return mustRange(t, "2024-01-10T00:00:00Z", "2024-01-20T00:00:00Z") },
+			at:     "2024-01-20T00:00:00Z",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.period(t).Contains(mustParse(t, tt.at)); got != tt.want {
+				t.Errorf("Contains(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeriodNextPrev(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	minsk := mustLoadLocation(t, "Europe/Minsk")
+
+	tests := []struct {
+		name                    string
+		period                  func(t *testing.T) Period
+		wantNextStart, wantNext string
+		wantPrevStart, wantPrev string
+	}{
+		{
+			name:          "day at year end",
+			period:        func(t *testing.T) Period { return NewDay(mustParse(t, "2024-12-31T12:00:00Z"), time.UTC) },
+			wantNextStart: "2025-01-01T00:00:00Z", wantNext: "2025-01-02T00:00:00Z",
+			wantPrevStart: "2024-12-30T00:00:00Z", wantPrev: "2024-12-31T00:00:00Z",
+		},
+		{
+			name:          "week across year end",
+			period:        func(t *testing.T) Period { return NewWeek(mustParse(t, "2024-12-31T12:00:00Z"), time.UTC, time.Monday) },
+			wantNextStart: "2025-01-06T00:00:00Z", wantNext: "2025-01-13T00:00:00Z",
+			wantPrevStart: "2024-12-23T00:00:00Z", wantPrev: "2024-12-30T00:00:00Z",
+		},
+		{
+			name:          "december to january",
+			period:        func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-12-15T12:00:00Z"), time.UTC) },
+			wantNextStart: "2025-01-01T00:00:00Z", wantNext: "2025-02-01T00:00:00Z",
+			wantPrevStart: "2024-11-01T00:00:00Z", wantPrev: "2024-12-01T00:00:00Z",
+		},
+		{
+			name:          "january to december",
+			period:        func(t *testing.T) Period { return NewMonth(mustParse(t, "2025-01-31T12:00:00Z"), time.UTC) },
+			wantNextStart: "2025-02-01T00:00:00Z", wantNext: "2025-03-01T00:00:00Z",
+			wantPrevStart: "2024-12-01T00:00:00Z", wantPrev: "2025-01-01T00:00:00Z",
+		},
+		{
+			name:          "fourth quarter to first",
+			period:        func(t *testing.T) Period { return NewQuarter(mustParse(t, "2024-11-30T12:00:00Z"), time.UTC) },
+			wantNextStart: "2025-01-01T00:00:00Z", wantNext: "2025-04-01T00:00:00Z",
+			wantPrevStart: "2024-07-01T00:00:00Z", wantPrev: "2024-10-01T00:00:00Z",
+		},
+		{
+			name:          "first quarter to fourth",
+			period:        func(t *testing.T) Period { return NewQuarter(mustParse(t, "2025-03-31T12:00:00Z"), time.UTC) },
+			wantNextStart: "2025-04-01T00:00:00Z", wantNext: "2025-07-01T00:00:00Z",
+			wantPrevStart: "2024-10-01T00:00:00Z", wantPrev: "2025-01-01T00:00:00Z",
+		},
+		{
+			name:          "second quarter",
+			period:        func(t *testing.T) Period { return NewQuarter(mustParse(t, "2024-06-30T23:59:59Z"), time.UTC) },
+			wantNextStart: "2024-07-01T00:00:00Z", wantNext: "2024-10-01T00:00:00Z",
+			wantPrevStart: "2024-01-01T00:00:00Z", wantPrev: "2024-04-01T00:00:00Z",
+		},
+		{
+			name:          "year",
+			period:        func(t *testing.T) Period { return NewYear(mustParse(t, "2024-07-01T12:00:00Z"), time.UTC) },
+			wantNextStart: "2025-01-01T00:00:00Z", wantNext: "2026-01-01T00:00:00Z",
+			wantPrevStart: "2023-01-01T00:00:00Z", wantPrev: "2024-01-01T00:00:00Z",
+		},
+		{
+			name:          "custom range keeps its length",
+			period:        func(t *testing.T) Period { return mustRange(t, "2024-01-10T00:00:00Z", "2024-01-20T00:00:00Z") },
+			wantNextStart: "2024-01-20T00:00:00Z", wantNext: "2024-01-30T00:00:00Z",
+			wantPrevStart: "2023-12-31T00:00:00Z", wantPrev: "2024-01-10T00:00:00Z",
+		},
+		{
+			name:          "day before DST start in Berlin",
+			period:        func(t *testing.T) Period { return NewDay(mustParse(t, "2024-03-30T12:00:00+01:00"), berlin) },
+			wantNextStart: "2024-03-31T00:00:00+01:00", wantNext: "2024-04-01T00:00:00+02:00",
+			wantPrevStart: "2024-03-29T00:00:00+01:00", wantPrev: "2024-03-30T00:00:00+01:00",
+		},
+		{
+			name:          "month across DST start in Berlin",
+			period:        func(t *testing.T) Period { return NewMonth(mustParse(t, "2024-03-15T12:00:00+01:00"), berlin) },
+			wantNextStart: "2024-04-01T00:00:00+02:00", wantNext: "2024-05-01T00:00:00+02:00",
+			wantPrevStart: "2024-02-01T00:00:00+01:00", wantPrev: "2024-03-01T00:00:00+01:00",
+		},
+		{
+			name:          "quarter across year end in Minsk",
+			period:        func(t *testing.T) Period { return NewQuarter(mustParse(t, "2024-12-31T22:00:00Z"), minsk) },
+			wantNextStart: "2025-04-01T00:00:00+03:00", wantNext: "2025-07-01T00:00:00+03:00",
+			wantPrevStart: "2024-10-01T00:00:00+03:00", wantPrev: "2025-01-01T00:00:00+03:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.period(t)
+			next := p.Next()
+			if next.Unit() != p.Unit() {
+				t.Errorf("Next().Unit() = %s, want %s", next.Unit(), p.Unit())
+			}
+			checkBounds(t, next, tt.wantNextStart, tt.wantNext)
+			checkBounds(t, p.Prev(), tt.wantPrevStart, tt.wantPrev)
+			checkBounds(t, next.Prev(), p.Start().Format(time.RFC3339), p.End().Format(time.RFC3339))
+		})
+	}
+}
@@ -49,7 +49,7 @@ var (
 var labels []string
 
 func init() {
-	labels = []string{"start", "cat", "curr", "report", "add_tbl", "add_cat", "add_rec", "choice_currency", "set_limit"}
+	labels = []string{"start", "cat", "curr", "report", "add_tbl", "add_cat", "add_rec", "choice_currency", "set_limit", "rates", "convert", "rate_alert"}
 
 	http.Handle("/", promhttp.Handler())
 
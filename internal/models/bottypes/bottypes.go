@@ -1,6 +1,10 @@
 package bottypes
 
-import "time"
+import (
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/money"
+)
 
 type Empty struct{}
 
@@ -9,16 +13,42 @@ type UserCategorySet map[string]Empty
 
 // Тип для записей о тратах.
 type UserDataRecord struct {
-	UserID   int64
-	Category string
-	Sum      float64
-	Period   time.Time
+	UserID      int64
+	Category    string
+	Sum         money.Money // Сумма в основной валюте.
+	OriginalSum money.Money // Сумма в валюте ввода (пустая для записей без исходной суммы).
+	Comment     string
+	Period      time.Time
+	ExternalID  string // Идентификатор операции в загруженной выписке (пустой для записей, введенных вручную).
 }
 
 // Тип для записей отчета.
 type UserDataReportRecord struct {
-	Category string
-	Sum      float64
+	CategoryID int64
+	Category   string
+	Sum        money.Money
+}
+
+// Порядок записей о расходах в выборке по страницам.
+const (
+	RecordsOrderSum  = "sum"  // По убыванию суммы.
+	RecordsOrderDate = "date" // От новых записей к старым.
+)
+
+// Тип для параметров выборки записей о расходах по страницам.
+type RecordsQuery struct {
+	From       time.Time // Начало периода.
+	To         time.Time // Конец периода (не включается).
+	CategoryID int64     // Категория (0 - все категории).
+	Order      string    // Порядок записей (RecordsOrderSum, RecordsOrderDate).
+	Limit      int
+	Offset     int
+}
+
+// Тип для суммы расходов за день или месяц (для графика расходов).
+type UserDataDateRecord struct {
+	Date time.Time // Начало дня или месяца в часовом поясе пользователя.
+	Sum  money.Money
 }
 
 // Типы для описания состава кнопок телеграм сообщения.
@@ -32,4 +62,80 @@ type TgInlineButton struct {
 type TgRowButtons []TgInlineButton
 
 // Тип для хранения курса валюты в формате "USD" = 0.01659657
-type ExchangeRate map[string]float64
+type ExchangeRate map[string]money.Rate
+
+// Виды подписок на изменение курса валюты.
+const (
+	RateAlertAbove  = "above"  // Курс выше порога.
+	RateAlertBelow  = "below"  // Курс ниже порога.
+	RateAlertChange = "change" // Изменение курса за день больше порога (в процентах).
+)
+
+// Тип для подписки на изменение курса валюты.
+type RateAlert struct {
+	ID            int64
+	UserID        int64
+	Currency      string     // Отслеживаемая валюта.
+	BaseCurrency  string     // Валюта, в которой выражен курс.
+	Kind          string     // Вид подписки (RateAlertAbove, RateAlertBelow, RateAlertChange).
+	Threshold     money.Rate // Пороговый курс или процент изменения.
+	Triggered     bool       // Условие выполнено, уведомление отправлено.
+	LastRatesDate time.Time  // Дата курсов последнего уведомления об изменении.
+}
+
+// Виды отчетов.
+const (
+	ReportKindText = "text" // Текстовая таблица по категориям.
+	ReportKindPie  = "pie"  // Круговая диаграмма по категориям.
+	ReportKindBar  = "bar"  // График расходов по дням или месяцам.
+	// ReportKindCompare Сравнение расходов по категориям с предыдущим периодом.
+	ReportKindCompare = "compare"
+)
+
+// Периодичность регулярных отчетов (дайджестов).
+const (
+	DigestDaily   = "daily"   // Каждый день за прошлый день.
+	DigestWeekly  = "weekly"  // Каждую неделю за прошлую неделю.
+	DigestMonthly = "monthly" // Каждый месяц за прошлый месяц.
+)
+
+// Тип для подписки на регулярный отчет.
+type Digest struct {
+	ID        int64
+	UserID    int64
+	Frequency string       // Периодичность (DigestDaily, DigestWeekly, DigestMonthly).
+	Weekday   time.Weekday // День недели еженедельного отчета.
+	Day       int          // День месяца ежемесячного отчета (1-28).
+	SendTime  int          // Время отправки в минутах от начала дня в часовом поясе пользователя.
+	NextRunAt time.Time    // Время следующей отправки.
+	LastRunAt time.Time    // Время последней отправки (пустое, если отчет еще не отправлялся).
+}
+
+// Статусы запроса на формирование отчета.
+const (
+	ReportStatusQueued  = "queued"  // Запрос поставлен в очередь.
+	ReportStatusRunning = "running" // Отчет формируется.
+	ReportStatusDone    = "done"    // Отчет отправлен пользователю.
+	ReportStatusFailed  = "failed"  // Запрос не удалось поставить в очередь или отчет не сформирован, и очередь не повторит запрос.
+	ReportStatusTimeout = "timeout" // Отчет не сформирован за отведенное время.
+)
+
+// Тип для состояния запроса на формирование отчета.
+type ReportRequestStatus struct {
+	ID          string // ID запроса (см. reports.Request).
+	UserID      int64
+	Period      string // Ключ периода отчета.
+	Kind        string // Вид отчета: text, pie или bar.
+	Status      string // Статус запроса (ReportStatusQueued, ...).
+	Error       string // Последняя ошибка формирования отчета.
+	RequestedAt time.Time
+	UpdatedAt   time.Time
+}
+
+// Тип для сопоставления колонок банковской выписки (сохраняется для каждого банка).
+type ImportMapping struct {
+	BankKey           string // Признак банка - отпечаток заголовка выписки.
+	DateColumn        int    // Номер колонки с датой операции.
+	AmountColumn      int    // Номер колонки с суммой операции.
+	DescriptionColumn int    // Номер колонки с описанием операции (-1, если колонки нет).
+}
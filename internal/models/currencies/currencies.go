@@ -0,0 +1,315 @@
+package currencies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+// RatesProvider Интерфейс источника курсов валют.
+type RatesProvider interface {
+	GetExchangeRates(ctx context.Context, mainCurrency string, currencies []string, date time.Time) (bottypes.ExchangeRate, time.Time, error)
+}
+
+// HistoryProvider Интерфейс источника, отдающего курсы за каждый день периода (с заполнением пропусков).
+type HistoryProvider interface {
+	GetHistoricalRates(ctx context.Context, mainCurrency string, currencies []string, from, to time.Time) (map[time.Time]bottypes.ExchangeRate, error)
+}
+
+// RatesStorage Интерфейс хранилища последних известных курсов валют.
+type RatesStorage interface {
+	SaveExchangeRates(ctx context.Context, mainCurrency string, rates bottypes.ExchangeRate, ratesDate time.Time, updatedAt time.Time) error
+	GetLastExchangeRates(ctx context.Context, mainCurrency string) (bottypes.ExchangeRate, time.Time, time.Time, error)
+	GetExchangeRatesOnDate(ctx context.Context, mainCurrency string, date time.Time) (bottypes.ExchangeRate, time.Time, error)
+}
+
+// maxHistoryGap Максимальный разрыв между запрошенной датой и датой сохраненных курсов,
+// при котором сохраненные курсы считаются курсами на запрошенную дату (выходные, праздники).
+const maxHistoryGap = 7 * 24 * time.Hour
+
+var errNoProvider = errors.New("rates provider is not set")
+
+// Метрики.
+var (
+	RatesUpdatedTime = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tg",
+		Subsystem: "currencies",
+		Name:      "rates_updated_timestamp_seconds", // Время получения используемых курсов из источника.
+	})
+	RatesStale = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tg",
+		Subsystem: "currencies",
+		Name:      "rates_stale", // Признак устаревания курсов (1 - курсы старше допустимого возраста).
+	})
+)
+
+// ExchangeRates Сервис курсов валют относительно основной валюты.
+type ExchangeRates struct {
+	mu             sync.RWMutex
+	provider       RatesProvider
+	storage        RatesStorage
+	mainCurrency   string
+	currenciesName []string
+	maxAge         time.Duration // Допустимый возраст курсов, после которого они считаются устаревшими.
+	rates          bottypes.ExchangeRate
+	ratesDate      time.Time // Дата, на которую установлены курсы.
+	updatedAt      time.Time // Время получения курсов из источника.
+	listeners      []func(ctx context.Context)
+}
+
+// New Создание сервиса курсов. Без источника (provider == nil) курсы загружаются только из хранилища.
+func New(provider RatesProvider, storage RatesStorage, mainCurrency string, currenciesName []string, maxAge time.Duration) *ExchangeRates {
+	return &ExchangeRates{
+		provider:       provider,
+		storage:        storage,
+		mainCurrency:   mainCurrency,
+		currenciesName: currenciesName,
+		maxAge:         maxAge,
+		rates:          bottypes.ExchangeRate{mainCurrency: money.OneRate},
+	}
+}
+
+// UpdateCurrencies Загрузка актуальных курсов валют из источника и сохранение их в хранилище.
+// При недоступности источника продолжают использоваться последние известные курсы.
+func (e *ExchangeRates) UpdateCurrencies(ctx context.Context) error {
+	defer e.updateMetrics()
+
+	if e.provider == nil {
+		return errNoProvider
+	}
+
+	rates, ratesDate, err := e.provider.GetExchangeRates(ctx, e.mainCurrency, e.currenciesName, time.Time{})
+	if err != nil {
+		if errCache := e.UpdateCurrenciesFromStorage(ctx); errCache != nil {
+			logger.Error("Error loading last known exchange rates", "err", errCache)
+		}
+		return fmt.Errorf("get exchange rates: %w", err)
+	}
+	updatedAt := time.Now()
+
+	e.setRates(rates, ratesDate, updatedAt)
+	logger.Info("Exchange rates updated", "date", ratesDate.Format("2006-01-02"), "rates", rates)
+
+	errSave := e.storage.SaveExchangeRates(ctx, e.mainCurrency, rates, ratesDate, updatedAt)
+
+	for _, listener := range e.listeners {
+		listener(ctx)
+	}
+
+	if errSave != nil {
+		return fmt.Errorf("save exchange rates: %w", errSave)
+	}
+	return nil
+}
+
+// AddUpdateListener Добавление функции, вызываемой после каждого обновления курсов из источника.
+// Функции должны быть добавлены до запуска обновления курсов.
+func (e *ExchangeRates) AddUpdateListener(listener func(ctx context.Context)) {
+	e.listeners = append(e.listeners, listener)
+}
+
+// UpdateCurrenciesFromStorage Загрузка последних известных курсов из хранилища, если они новее используемых.
+func (e *ExchangeRates) UpdateCurrenciesFromStorage(ctx context.Context) error {
+	defer e.updateMetrics()
+
+	rates, ratesDate, updatedAt, err := e.storage.GetLastExchangeRates(ctx, e.mainCurrency)
+	if err != nil {
+		return fmt.Errorf("get last exchange rates: %w", err)
+	}
+	if len(rates) == 0 || !updatedAt.After(e.GetRatesUpdateTime()) {
+		return nil
+	}
+
+	rates[e.mainCurrency] = money.OneRate
+	e.setRates(rates, ratesDate, updatedAt)
+	logger.Info("Exchange rates loaded from storage", "date", ratesDate.Format("2006-01-02"), "updated", updatedAt)
+	return nil
+}
+
+// AutoUpdate Периодическое обновление курсов валют из источника до отмены контекста.
+func (e *ExchangeRates) AutoUpdate(ctx context.Context, period time.Duration) {
+	runPeriodically(ctx, period, func() {
+		if err := e.UpdateCurrencies(ctx); err != nil {
+			logger.Error("Error updating exchange rates", "err", err)
+		}
+	})
+}
+
+// AutoUpdateFromStorage Периодическая загрузка курсов валют из хранилища до отмены контекста.
+func (e *ExchangeRates) AutoUpdateFromStorage(ctx context.Context, period time.Duration) {
+	runPeriodically(ctx, period, func() {
+		if err := e.UpdateCurrenciesFromStorage(ctx); err != nil {
+			logger.Error("Error updating exchange rates from storage", "err", err)
+		}
+	})
+}
+
+// GetRatesUpdateTime Время получения используемых курсов из источника (нулевое, если курсов нет).
+func (e *ExchangeRates) GetRatesUpdateTime() time.Time {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.updatedAt
+}
+
+// IsRatesStale Проверка, что используемые курсы старше допустимого возраста.
+func (e *ExchangeRates) IsRatesStale() bool {
+	updatedAt := e.GetRatesUpdateTime()
+	return updatedAt.IsZero() || (e.maxAge > 0 && time.Since(updatedAt) > e.maxAge)
+}
+
+func (e *ExchangeRates) setRates(rates bottypes.ExchangeRate, ratesDate time.Time, updatedAt time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rates = rates
+	e.ratesDate = ratesDate
+	e.updatedAt = updatedAt
+}
+
+func (e *ExchangeRates) updateMetrics() {
+	if updatedAt := e.GetRatesUpdateTime(); !updatedAt.IsZero() {
+		RatesUpdatedTime.Set(float64(updatedAt.Unix()))
+	}
+	if e.IsRatesStale() {
+		RatesStale.Set(1)
+	} else {
+		RatesStale.Set(0)
+	}
+}
+
+func runPeriodically(ctx context.Context, period time.Duration, f func()) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		f()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetRatesDate Дата, на которую источник установил используемые курсы.
+func (e *ExchangeRates) GetRatesDate() time.Time {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.ratesDate
+}
+
+// GetExchangeRatesOnDate Курсы валют на дату: из хранилища, а при их отсутствии - из источника.
+// Если источник отдает историю курсов, в хранилище сохраняются курсы за все дни перед датой, которых в нем не было.
+func (e *ExchangeRates) GetExchangeRatesOnDate(ctx context.Context, date time.Time) (bottypes.ExchangeRate, error) {
+	rates, ratesDate, err := e.storage.GetExchangeRatesOnDate(ctx, e.mainCurrency, date)
+	if err != nil {
+		logger.Error("Error getting exchange rates from storage", "date", date, "err", err)
+	} else if len(rates) > 0 && date.Sub(ratesDate) < maxHistoryGap {
+		return rates, nil
+	}
+
+	if e.provider == nil {
+		return nil, errNoProvider
+	}
+
+	if _, ok := e.provider.(HistoryProvider); ok {
+		rates, err := e.fillHistoricalRates(ctx, date.Add(-maxHistoryGap), date)
+		if err == nil {
+			return rates, nil
+		}
+		logger.Error("Error getting historical exchange rates", "date", date, "err", err)
+	}
+
+	rates, ratesDate, err = e.provider.GetExchangeRates(ctx, e.mainCurrency, e.currenciesName, date)
+	if err != nil {
+		return nil, fmt.Errorf("get exchange rates on %s: %w", date.Format("2006-01-02"), err)
+	}
+	if err := e.storage.SaveExchangeRates(ctx, e.mainCurrency, rates, ratesDate, time.Now()); err != nil {
+		logger.Error("Error saving exchange rates", "date", ratesDate, "err", err)
+	}
+	return rates, nil
+}
+
+// fillHistoricalRates Сохранение в хранилище курсов за каждый день периода. Возвращает курсы на последний день.
+// Время получения курсов сохраняется равным их дате, чтобы исторические курсы не заменили последние известные.
+func (e *ExchangeRates) fillHistoricalRates(ctx context.Context, from, to time.Time) (bottypes.ExchangeRate, error) {
+	history, err := e.GetHistoricalRates(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var last bottypes.ExchangeRate
+	var lastDate time.Time
+	for date, rates := range history {
+		if err := e.storage.SaveExchangeRates(ctx, e.mainCurrency, rates, date, date); err != nil {
+			logger.Error("Error saving exchange rates", "date", date, "err", err)
+		}
+		if date.After(lastDate) {
+			last, lastDate = rates, date
+		}
+	}
+	if last == nil {
+		return nil, fmt.Errorf("no historical exchange rates on %s", to.Format("2006-01-02"))
+	}
+	return last, nil
+}
+
+// GetHistoricalRates Курсы валют за каждый день периода, если источник поддерживает историю.
+func (e *ExchangeRates) GetHistoricalRates(ctx context.Context, from, to time.Time) (map[time.Time]bottypes.ExchangeRate, error) {
+	history, ok := e.provider.(HistoryProvider)
+	if !ok {
+		return nil, errors.New("rates provider does not support history")
+	}
+	return history.GetHistoricalRates(ctx, e.mainCurrency, e.currenciesName, from, to)
+}
+
+// ConvertSumFromBaseToCurrency Конвертация суммы из основной валюты в указанную.
+func (e *ExchangeRates) ConvertSumFromBaseToCurrency(currencyName string, sum money.Money) (money.Money, error) {
+	if sum.Currency() != e.mainCurrency {
+		return money.Money{}, fmt.Errorf("sum in %s is not in main currency %s", sum.Currency(), e.mainCurrency)
+	}
+	rate, err := e.GetExchangeRate(currencyName)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return sum.Convert(rate, currencyName)
+}
+
+// ConvertSumFromCurrencyToBase Конвертация суммы из ее валюты в основную.
+func (e *ExchangeRates) ConvertSumFromCurrencyToBase(sum money.Money) (money.Money, error) {
+	rate, err := e.GetExchangeRate(sum.Currency())
+	if err != nil {
+		return money.Money{}, err
+	}
+	return sum.ConvertInverse(rate, e.mainCurrency)
+}
+
+// GetExchangeRate Курс валюты (количество единиц валюты за 1 единицу основной).
+func (e *ExchangeRates) GetExchangeRate(currencyName string) (money.Rate, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	rate, ok := e.rates[currencyName]
+	if !ok || !rate.IsPositive() {
+		return money.Rate{}, fmt.Errorf("no exchange rate for currency %s", currencyName)
+	}
+	return rate, nil
+}
+
+func (e *ExchangeRates) GetMainCurrency() string {
+	return e.mainCurrency
+}
+
+func (e *ExchangeRates) GetCurrenciesList() []string {
+	list := make([]string, len(e.currenciesName))
+	copy(list, e.currenciesName)
+	return list
+}
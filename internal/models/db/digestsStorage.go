@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/dbutils"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+type DigestDB struct {
+	ID        int64        `db:"id"`
+	UserID    int64        `db:"user_id"`
+	Frequency string       `db:"frequency"`
+	Weekday   int          `db:"weekday"`
+	Day       int          `db:"day"`
+	SendTime  int          `db:"send_time"`
+	NextRunAt time.Time    `db:"next_run_at"`
+	LastRunAt sql.NullTime `db:"last_run_at"`
+}
+
+const digestColumns = `id, user_id, frequency, weekday, day, send_time, next_run_at, last_run_at`
+
+// InsertDigest Добавление подписки пользователя на регулярный отчет.
+// Возвращает false, если такая подписка уже есть.
+func (storage *UserStorage) InsertDigest(ctx context.Context, digest bottypes.Digest, userName string) (bool, error) {
+	if _, err := storage.CheckIfUserExistAndAdd(ctx, digest.UserID, userName); err != nil {
+		return false, err
+	}
+
+	const sqlString = `
+		INSERT INTO digests (user_id, frequency, weekday, day, send_time, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, frequency, weekday, day, send_time) DO NOTHING
+		RETURNING id;`
+
+	var ids []int64
+	if err := dbutils.Select(ctx, storage.db, &ids, sqlString,
+		digest.UserID, digest.Frequency, int(digest.Weekday), digest.Day, digest.SendTime, digest.NextRunAt); err != nil {
+		return false, err
+	}
+	return len(ids) > 0, nil
+}
+
+func (storage *UserStorage) GetUserDigests(ctx context.Context, userID int64) ([]bottypes.Digest, error) {
+	const sqlString = `SELECT ` + digestColumns + ` FROM digests WHERE user_id = $1 ORDER BY id;`
+	return storage.selectDigests(ctx, sqlString, userID)
+}
+
+func (storage *UserStorage) DeleteDigest(ctx context.Context, userID int64, digestID int64) error {
+	const sqlString = `DELETE FROM digests WHERE user_id = $1 AND id = $2;`
+
+	_, err := dbutils.Exec(ctx, storage.db, sqlString, userID, digestID)
+	return err
+}
+
+// SetDigestNextRun Изменение времени следующей отправки (например, после смены часового пояса пользователя).
+func (storage *UserStorage) SetDigestNextRun(ctx context.Context, userID int64, digestID int64, nextRunAt time.Time) error {
+	const sqlString = `UPDATE digests SET next_run_at = $3 WHERE user_id = $1 AND id = $2;`
+
+	_, err := dbutils.Exec(ctx, storage.db, sqlString, userID, digestID, nextRunAt)
+	return err
+}
+
+// GetDueDigests Получение подписок всех пользователей, время отправки которых наступило.
+func (storage *UserStorage) GetDueDigests(ctx context.Context, now time.Time) ([]bottypes.Digest, error) {
+	const sqlString = `SELECT ` + digestColumns + ` FROM digests WHERE next_run_at <= $1 ORDER BY next_run_at, id;`
+	return storage.selectDigests(ctx, sqlString, now)
+}
+
+// ClaimDigest Перенос отправки на следующее время, если подписку еще не забрал другой экземпляр бота.
+// Возвращает false, если время отправки уже изменено.
+func (storage *UserStorage) ClaimDigest(ctx context.Context, digestID int64, runAt time.Time, nextRunAt time.Time, now time.Time) (bool, error) {
+	const sqlString = `
+		UPDATE digests SET next_run_at = $3, last_run_at = $4
+		WHERE id = $1 AND next_run_at = $2
+		RETURNING id;`
+
+	var ids []int64
+	if err := dbutils.Select(ctx, storage.db, &ids, sqlString, digestID, runAt, nextRunAt, now); err != nil {
+		return false, err
+	}
+	return len(ids) > 0, nil
+}
+
+func (storage *UserStorage) selectDigests(ctx context.Context, sqlString string, args ...any) ([]bottypes.Digest, error) {
+	var rows []DigestDB
+	if err := dbutils.Select(ctx, storage.db, &rows, sqlString, args...); err != nil {
+		return nil, err
+	}
+
+	digests := make([]bottypes.Digest, 0, len(rows))
+	for _, row := range rows {
+		digests = append(digests, bottypes.Digest{
+			ID:        row.ID,
+			UserID:    row.UserID,
+			Frequency: row.Frequency,
+			Weekday:   time.Weekday(row.Weekday),
+			Day:       row.Day,
+			SendTime:  row.SendTime,
+			NextRunAt: row.NextRunAt,
+			LastRunAt: row.LastRunAt.Time,
+		})
+	}
+	return digests, nil
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/shoksin/financesBot/internal/helpers/dbutils"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+type ImportMappingDB struct {
+	BankKey           string `db:"bank_key"`
+	DateColumn        int    `db:"date_column"`
+	AmountColumn      int    `db:"amount_column"`
+	DescriptionColumn int    `db:"description_column"`
+}
+
+type ImportCategoryMappingDB struct {
+	SourceCategory string `db:"source_category"`
+	Category       string `db:"category"`
+}
+
+// GetImportMapping Получение сохраненного сопоставления колонок выписки банка.
+// Возвращает false, если для банка сопоставление еще не сохранено.
+func (storage *UserStorage) GetImportMapping(ctx context.Context, userID int64, bankKey string) (bottypes.ImportMapping, bool, error) {
+	const sqlString = `
+		SELECT bank_key, date_column, amount_column, description_column
+		FROM import_mappings
+		WHERE user_id = $1 AND bank_key = $2;`
+
+	var rows []ImportMappingDB
+	if err := dbutils.Select(ctx, storage.db, &rows, sqlString, userID, bankKey); err != nil {
+		return bottypes.ImportMapping{}, false, err
+	}
+	if len(rows) == 0 {
+		return bottypes.ImportMapping{}, false, nil
+	}
+	return bottypes.ImportMapping{
+		BankKey:           rows[0].BankKey,
+		DateColumn:        rows[0].DateColumn,
+		AmountColumn:      rows[0].AmountColumn,
+		DescriptionColumn: rows[0].DescriptionColumn,
+	}, true, nil
+}
+
+// SetImportMapping Сохранение сопоставления колонок выписки банка.
+func (storage *UserStorage) SetImportMapping(ctx context.Context, userID int64, mapping bottypes.ImportMapping, userName string) error {
+	if _, err := storage.CheckIfUserExistAndAdd(ctx, userID, userName); err != nil {
+		return err
+	}
+
+	const sqlString = `
+		INSERT INTO import_mappings (user_id, bank_key, date_column, amount_column, description_column)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, bank_key) DO UPDATE
+		SET date_column = EXCLUDED.date_column,
+			amount_column = EXCLUDED.amount_column,
+			description_column = EXCLUDED.description_column,
+			updated_at = now();`
+
+	_, err := dbutils.Exec(ctx, storage.db, sqlString, userID, mapping.BankKey, mapping.DateColumn, mapping.AmountColumn, mapping.DescriptionColumn)
+	return err
+}
+
+// GetImportCategoryMappings Получение сопоставления категорий из выгрузок с категориями пользователя
+// (ключ - категория из файла в нижнем регистре).
+func (storage *UserStorage) GetImportCategoryMappings(ctx context.Context, userID int64) (map[string]string, error) {
+	const sqlString = `SELECT source_category, category FROM import_category_mappings WHERE user_id = $1;`
+
+	var rows []ImportCategoryMappingDB
+	if err := dbutils.Select(ctx, storage.db, &rows, sqlString, userID); err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]string, len(rows))
+	for _, row := range rows {
+		res[row.SourceCategory] = row.Category
+	}
+	return res, nil
+}
+
+// SetImportCategoryMapping Сохранение категории пользователя для категории из выгрузки.
+func (storage *UserStorage) SetImportCategoryMapping(ctx context.Context, userID int64, sourceCategory string, category string, userName string) error {
+	if _, err := storage.CheckIfUserExistAndAdd(ctx, userID, userName); err != nil {
+		return err
+	}
+
+	const sqlString = `
+		INSERT INTO import_category_mappings (user_id, source_category, category)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, source_category) DO UPDATE
+		SET category = EXCLUDED.category, updated_at = now();`
+
+	_, err := dbutils.Exec(ctx, storage.db, sqlString, userID, strings.ToLower(sourceCategory), category)
+	return err
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/shoksin/financesBot/internal/helpers/dbutils"
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+type RateAlertDB struct {
+	ID            int64        `db:"id"`
+	UserID        int64        `db:"user_id"`
+	Currency      string       `db:"currency"`
+	BaseCurrency  string       `db:"base_currency"`
+	Kind          string       `db:"kind"`
+	Threshold     string       `db:"threshold"`
+	Triggered     bool         `db:"triggered"`
+	LastRatesDate sql.NullTime `db:"last_rates_date"`
+}
+
+const rateAlertColumns = `id, user_id, currency, base_currency, kind, threshold, triggered, last_rates_date`
+
+// InsertRateAlert Добавление подписки пользователя на изменение курса.
+func (storage *UserStorage) InsertRateAlert(ctx context.Context, alert bottypes.RateAlert, userName string) error {
+	if _, err := storage.CheckIfUserExistAndAdd(ctx, alert.UserID, userName); err != nil {
+		return err
+	}
+
+	const sqlString = `
+		INSERT INTO rate_alerts (user_id, currency, base_currency, kind, threshold)
+		VALUES ($1, $2, $3, $4, $5);`
+
+	_, err := dbutils.Exec(ctx, storage.db, sqlString, alert.UserID, alert.Currency, alert.BaseCurrency, alert.Kind, alert.Threshold.String())
+	return err
+}
+
+func (storage *UserStorage) GetUserRateAlerts(ctx context.Context, userID int64) ([]bottypes.RateAlert, error) {
+	const sqlString = `SELECT ` + rateAlertColumns + ` FROM rate_alerts WHERE user_id = $1 ORDER BY id;`
+	return storage.selectRateAlerts(ctx, sqlString, userID)
+}
+
+func (storage *UserStorage) DeleteRateAlert(ctx context.Context, userID int64, alertID int64) error {
+	const sqlString = `DELETE FROM rate_alerts WHERE user_id = $1 AND id = $2;`
+
+	_, err := dbutils.Exec(ctx, storage.db, sqlString, userID, alertID)
+	return err
+}
+
+// GetRateAlerts Получение подписок всех пользователей.
+func (storage *UserStorage) GetRateAlerts(ctx context.Context) ([]bottypes.RateAlert, error) {
+	const sqlString = `SELECT ` + rateAlertColumns + ` FROM rate_alerts ORDER BY id;`
+	return storage.selectRateAlerts(ctx, sqlString)
+}
+
+// UpdateRateAlertState Сохранение состояния подписки после проверки.
+func (storage *UserStorage) UpdateRateAlertState(ctx context.Context, alert bottypes.RateAlert) error {
+	const sqlString = `UPDATE rate_alerts SET triggered = $2, last_rates_date = $3 WHERE id = $1;`
+
+	lastRatesDate := sql.NullTime{Time: alert.LastRatesDate, Valid: !alert.LastRatesDate.IsZero()}
+	_, err := dbutils.Exec(ctx, storage.db, sqlString, alert.ID, alert.Triggered, lastRatesDate)
+	return err
+}
+
+func (storage *UserStorage) selectRateAlerts(ctx context.Context, sqlString string, args ...any) ([]bottypes.RateAlert, error) {
+	var rows []RateAlertDB
+	if err := dbutils.Select(ctx, storage.db, &rows, sqlString, args...); err != nil {
+		return nil, err
+	}
+
+	alerts := make([]bottypes.RateAlert, 0, len(rows))
+	for _, row := range rows {
+		threshold, err := money.ParseRate(row.Threshold)
+		if err != nil {
+			return nil, fmt.Errorf("parse threshold of rate alert %d: %w", row.ID, err)
+		}
+		alerts = append(alerts, bottypes.RateAlert{
+			ID:            row.ID,
+			UserID:        row.UserID,
+			Currency:      row.Currency,
+			BaseCurrency:  row.BaseCurrency,
+			Kind:          row.Kind,
+			Threshold:     threshold,
+			Triggered:     row.Triggered,
+			LastRatesDate: row.LastRatesDate.Time,
+		})
+	}
+	return alerts, nil
+}
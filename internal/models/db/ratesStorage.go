@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/shoksin/financesBot/internal/helpers/dbutils"
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+type ExchangeRateDB struct {
+	Currency  string    `db:"currency"`
+	Rate      string    `db:"rate"`
+	RatesDate time.Time `db:"rates_date"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type RatesStorage struct {
+	db *sqlx.DB
+}
+
+func NewRatesStorage(db *sqlx.DB) *RatesStorage {
+	return &RatesStorage{db: db}
+}
+
+// SaveExchangeRates Сохранение курсов валют, полученных из источника.
+func (storage *RatesStorage) SaveExchangeRates(ctx context.Context, mainCurrency string, rates bottypes.ExchangeRate, ratesDate time.Time, updatedAt time.Time) error {
+	const sqlString = `
+		INSERT INTO exchange_rates (main_currency, currency, rate, rates_date, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (main_currency, currency, rates_date) DO UPDATE
+		SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at;`
+
+	return dbutils.RunTx(ctx, storage.db, func(tx *sqlx.Tx) error {
+		for currency, rate := range rates {
+			if _, err := dbutils.Exec(ctx, tx, sqlString, mainCurrency, currency, rate.String(), ratesDate, updatedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetLastExchangeRates Получение последних известных курсов валют и времени их получения.
+func (storage *RatesStorage) GetLastExchangeRates(ctx context.Context, mainCurrency string) (bottypes.ExchangeRate, time.Time, time.Time, error) {
+	const sqlString = `
+		SELECT DISTINCT ON (currency) currency, rate, rates_date, updated_at
+		FROM exchange_rates
+		WHERE main_currency = $1
+		ORDER BY currency, updated_at DESC;`
+
+	var rows []ExchangeRateDB
+	if err := dbutils.Select(ctx, storage.db, &rows, sqlString, mainCurrency); err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+
+	rates := bottypes.ExchangeRate{}
+	var ratesDate, updatedAt time.Time
+	for _, row := range rows {
+		rate, err := money.ParseRate(row.Rate)
+		if err != nil {
+			return nil, time.Time{}, time.Time{}, err
+		}
+		rates[row.Currency] = rate
+		if row.UpdatedAt.After(updatedAt) {
+			updatedAt = row.UpdatedAt
+			ratesDate = row.RatesDate
+		}
+	}
+	return rates, ratesDate, updatedAt, nil
+}
+
+// GetExchangeRatesOnDate Получение курсов валют, установленных не позднее даты, и даты их установки.
+func (storage *RatesStorage) GetExchangeRatesOnDate(ctx context.Context, mainCurrency string, date time.Time) (bottypes.ExchangeRate, time.Time, error) {
+	const sqlString = `
+		SELECT DISTINCT ON (currency) currency, rate, rates_date, updated_at
+		FROM exchange_rates
+		WHERE main_currency = $1 AND rates_date <= $2
+		ORDER BY currency, rates_date DESC;`
+
+	var rows []ExchangeRateDB
+	if err := dbutils.Select(ctx, storage.db, &rows, sqlString, mainCurrency, date); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	rates := bottypes.ExchangeRate{}
+	var ratesDate time.Time
+	for _, row := range rows {
+		rate, err := money.ParseRate(row.Rate)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		rates[row.Currency] = rate
+		if row.RatesDate.After(ratesDate) {
+			ratesDate = row.RatesDate
+		}
+	}
+	return rates, ratesDate, nil
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/dbutils"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+type ReportRequestDB struct {
+	ID          string    `db:"id"`
+	UserID      int64     `db:"user_id"`
+	Period      string    `db:"period"`
+	Kind        string    `db:"kind"`
+	Status      string    `db:"status"`
+	LastError   string    `db:"last_error"`
+	RequestedAt time.Time `db:"requested_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+const reportRequestColumns = `id, user_id, period, kind, status, last_error, requested_at, updated_at`
+
+// InsertReportRequest Сохранение запроса на формирование отчета.
+// Возвращает false, если такой же отчет по тем же данным пользователя уже в очереди или формируется.
+func (storage *UserStorage) InsertReportRequest(ctx context.Context, req bottypes.ReportRequestStatus, userName string) (bool, error) {
+	if _, err := storage.CheckIfUserExistAndAdd(ctx, req.UserID, userName); err != nil {
+		return false, err
+	}
+
+	const sqlString = `
+		INSERT INTO report_requests (id, user_id, period, kind, data_version, requested_at)
+		SELECT $1, $2, $3, $4, COALESCE(MAX(d.id), 0), $5
+		FROM userdata d
+		WHERE d.user_id = $2
+		ON CONFLICT (user_id, period, kind, data_version) WHERE status IN ('queued', 'running') DO NOTHING
+		RETURNING id;`
+
+	var ids []string
+	if err := dbutils.Select(ctx, storage.db, &ids, sqlString, req.ID, req.UserID, req.Period, req.Kind, req.RequestedAt); err != nil {
+		return false, err
+	}
+	return len(ids) > 0, nil
+}
+
+// StartReportRequest Перевод запроса в статус running.
+// Возвращает false, если запрос не ожидает обработки (уже выполнен, выполняется другим обработчиком или просрочен).
+func (storage *UserStorage) StartReportRequest(ctx context.Context, requestID string) (bool, error) {
+	const sqlString = `
+		UPDATE report_requests SET status = 'running', updated_at = now()
+		WHERE id = $1 AND status = 'queued'
+		RETURNING id;`
+
+	var ids []string
+	if err := dbutils.Select(ctx, storage.db, &ids, sqlString, requestID); err != nil {
+		return false, err
+	}
+	return len(ids) > 0, nil
+}
+
+// FinishReportRequest Смена статуса запроса, который в очереди или формируется.
+func (storage *UserStorage) FinishReportRequest(ctx context.Context, requestID string, status string, errText string) error {
+	const sqlString = `
+		UPDATE report_requests SET status = $2, last_error = $3, updated_at = now()
+		WHERE id = $1 AND status IN ('queued', 'running');`
+
+	_, err := dbutils.Exec(ctx, storage.db, sqlString, requestID, status, errText)
+	return err
+}
+
+// GetUserReportRequests Получение последних запросов пользователя на формирование отчетов.
+func (storage *UserStorage) GetUserReportRequests(ctx context.Context, userID int64, limit int) ([]bottypes.ReportRequestStatus, error) {
+	const sqlString = `SELECT ` + reportRequestColumns + ` FROM report_requests WHERE user_id = $1 ORDER BY requested_at DESC LIMIT $2;`
+	return storage.selectReportRequests(ctx, sqlString, userID, limit)
+}
+
+// TimeoutReportRequests Перевод в статус timeout запросов, не выполненных за отведенное время.
+func (storage *UserStorage) TimeoutReportRequests(ctx context.Context, deadline time.Duration) ([]bottypes.ReportRequestStatus, error) {
+	const sqlString = `
+		UPDATE report_requests SET status = 'timeout', updated_at = now()
+		WHERE status IN ('queued', 'running') AND requested_at < now() - $1 * INTERVAL '1 second'
+		RETURNING ` + reportRequestColumns + `;`
+	return storage.selectReportRequests(ctx, sqlString, deadline.Seconds())
+}
+
+func (storage *UserStorage) selectReportRequests(ctx context.Context, sqlString string, args ...any) ([]bottypes.ReportRequestStatus, error) {
+	var rows []ReportRequestDB
+	if err := dbutils.Select(ctx, storage.db, &rows, sqlString, args...); err != nil {
+		return nil, err
+	}
+
+	res := make([]bottypes.ReportRequestStatus, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, bottypes.ReportRequestStatus{
+			ID:          row.ID,
+			UserID:      row.UserID,
+			Period:      row.Period,
+			Kind:        row.Kind,
+			Status:      row.Status,
+			Error:       row.LastError,
+			RequestedAt: row.RequestedAt,
+			UpdatedAt:   row.UpdatedAt,
+		})
+	}
+	return res, nil
+}
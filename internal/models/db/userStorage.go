@@ -2,34 +2,72 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/shoksin/financesBot/internal/helpers/dbutils"
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/helpers/timeutils"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
 )
 
 type UserDataReportRecordDB struct {
-	Category string  `db:"name"`
-	Sum      float64 `db:"sum"`
+	CategoryID int64  `db:"category_id"`
+	Category   string `db:"name"`
+	Sum        string `db:"sum"`
+	Currency   string `db:"currency"`
 }
 
+type UserDataRecordDB struct {
+	Category         string         `db:"name"`
+	Sum              string         `db:"sum"`
+	Currency         string         `db:"currency"`
+	OriginalSum      sql.NullString `db:"original_sum"`
+	OriginalCurrency sql.NullString `db:"original_currency"`
+	Comment          string         `db:"comment"`
+	Period           time.Time      `db:"period"`
+}
+
+type UserDataRecordPageDB struct {
+	UserDataRecordDB
+	Total int `db:"total"`
+}
+
+type PayeeCategoryDB struct {
+	Payee    string `db:"payee"`
+	Category string `db:"category"`
+}
+
+type UserDataDateRecordDB struct {
+	Date     time.Time `db:"date"`
+	Sum      string    `db:"sum"`
+	Currency string    `db:"currency"`
+}
+
+// ErrOverLimit Ошибка превышения бюджета при добавлении записи.
+var ErrOverLimit = errors.New("user limit exceeded")
+
 type UserStorage struct {
 	db              *sqlx.DB
 	defaultCurrency string
-	defaultLimits   float64
+	defaultLimits   money.Money // Бюджет по умолчанию в основной валюте.
+	defaultTimezone string      // Часовой пояс пользователей, не задавших свой.
 }
 
-func NewUserStorage(db *sqlx.DB, defaultCurrecny string, defaultLimits float64) *UserStorage {
-	return &UserStorage{db: db, defaultCurrency: defaultCurrecny, defaultLimits: defaultLimits}
+func NewUserStorage(db *sqlx.DB, defaultCurrecny string, defaultLimits money.Money, defaultTimezone string) *UserStorage {
+	return &UserStorage{db: db, defaultCurrency: defaultCurrecny, defaultLimits: defaultLimits, defaultTimezone: defaultTimezone}
 }
 
 func (storage *UserStorage) InsertUser(ctx context.Context, userID int64, userName string) error {
 	const sqlString = `
-		INSER INTO users (tg_id, name, currency, limits)
+		INSERT INTO users (tg_id, name, currency, limits)
 		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (tg_id) DO NOTHING;`
 
-	if _, err := dbutils.Exec(ctx, storage.db, sqlString, userID, userName, storage.defaultCurrency, storage.defaultLimits); err != nil {
+	if _, err := dbutils.Exec(ctx, storage.db, sqlString, userID, userName, storage.defaultCurrency, storage.defaultLimits.String()); err != nil {
 		return err
 	}
 	return nil
@@ -67,3 +105,368 @@ func (storage *UserStorage) CheckIfUserExistAndAdd(ctx context.Context, userID i
 	}
 	return true, nil
 }
+
+// InsertUserDataRecord Добавление записи о расходе с проверкой бюджета с начала периода limitPeriod.
+// Возвращает true, если запись не добавлена из-за превышения бюджета.
+func (storage *UserStorage) InsertUserDataRecord(ctx context.Context, userID int64, rec bottypes.UserDataRecord, userName string, limitPeriod timeutils.Period) (bool, error) {
+	if _, err := storage.CheckIfUserExistAndAdd(ctx, userID, userName); err != nil {
+		return false, err
+	}
+
+	isOverLimit := false
+	err := dbutils.RunTx(ctx, storage.db, func(tx *sqlx.Tx) error {
+		categoryID, err := insertCategory(ctx, tx, userID, rec.Category)
+		if err != nil {
+			return err
+		}
+
+		if isOverLimit, err = checkUserLimit(ctx, tx, userID, rec.Sum, limitPeriod); err != nil {
+			return err
+		}
+		if isOverLimit {
+			return ErrOverLimit
+		}
+
+		const sqlString = `
+			INSERT INTO userdata (user_id, category_id, sum, currency, period, original_sum, original_currency, comment, external_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+
+		var originalSum, originalCurrency sql.NullString
+		if rec.OriginalSum.Currency() != "" {
+			originalSum = sql.NullString{String: rec.OriginalSum.String(), Valid: true}
+			originalCurrency = sql.NullString{String: rec.OriginalSum.Currency(), Valid: true}
+		}
+		externalID := sql.NullString{String: rec.ExternalID, Valid: rec.ExternalID != ""}
+		_, err = dbutils.Exec(ctx, tx, sqlString, userID, categoryID, rec.Sum.String(), rec.Sum.Currency(), rec.Period,
+			originalSum, originalCurrency, rec.Comment, externalID)
+		return err
+	})
+	return isOverLimit, err
+}
+
+// GetImportedExternalIDs Получение идентификаторов операций из списка ids, которые уже загружены пользователем.
+func (storage *UserStorage) GetImportedExternalIDs(ctx context.Context, userID int64, ids []string) (map[string]bool, error) {
+	const sqlString = `SELECT external_id FROM userdata WHERE user_id = $1 AND external_id = ANY($2);`
+
+	var rows []string
+	if err := dbutils.Select(ctx, storage.db, &rows, sqlString, userID, ids); err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]bool, len(rows))
+	for _, id := range rows {
+		res[id] = true
+	}
+	return res, nil
+}
+
+// GetPayeeCategories Категории последних записей пользователя по комментарию (получателю платежа) в нижнем регистре.
+// Записи категории excludeCategory не учитываются.
+func (storage *UserStorage) GetPayeeCategories(ctx context.Context, userID int64, excludeCategory string) (map[string]string, error) {
+	const sqlString = `
+		SELECT DISTINCT ON (lower(d.comment)) lower(d.comment) AS payee, c.name AS category
+		FROM userdata d
+		JOIN usercategories c ON c.id = d.category_id
+		WHERE d.user_id = $1 AND d.comment <> '' AND c.name <> $2
+		ORDER BY lower(d.comment), d.period DESC;`
+
+	var rows []PayeeCategoryDB
+	if err := dbutils.Select(ctx, storage.db, &rows, sqlString, userID, excludeCategory); err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]string, len(rows))
+	for _, row := range rows {
+		res[row.Payee] = row.Category
+	}
+	return res, nil
+}
+
+// GetUserDataRecord Получение сумм расходов пользователя по категориям за период [from, to).
+func (storage *UserStorage) GetUserDataRecord(ctx context.Context, userID int64, from time.Time, to time.Time) ([]bottypes.UserDataReportRecord, error) {
+	const sqlString = `
+		SELECT c.id AS category_id, c.name, SUM(d.sum) AS sum, d.currency
+		FROM userdata d
+		JOIN usercategories c ON c.id = d.category_id
+		WHERE d.user_id = $1 AND d.period >= $2 AND d.period < $3
+		GROUP BY c.id, c.name, d.currency
+		ORDER BY SUM(d.sum) DESC, c.name;`
+
+	var rows []UserDataReportRecordDB
+	if err := dbutils.Select(ctx, storage.db, &rows, sqlString, userID, from, to); err != nil {
+		return nil, err
+	}
+
+	res := make([]bottypes.UserDataReportRecord, 0, len(rows))
+	for _, row := range rows {
+		sum, err := money.Parse(row.Sum, row.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("parse sum of category %s: %w", row.Category, err)
+		}
+		res = append(res, bottypes.UserDataReportRecord{CategoryID: row.CategoryID, Category: row.Category, Sum: sum})
+	}
+	return res, nil
+}
+
+// GetUserDataRecords Получение записей о расходах пользователя за период [from, to) в порядке дат.
+func (storage *UserStorage) GetUserDataRecords(ctx context.Context, userID int64, from time.Time, to time.Time) ([]bottypes.UserDataRecord, error) {
+	const sqlString = `
+		SELECT c.name, d.sum, d.currency, d.original_sum, d.original_currency, d.comment, d.period
+		FROM userdata d
+		JOIN usercategories c ON c.id = d.category_id
+		WHERE d.user_id = $1 AND d.period >= $2 AND d.period < $3
+		ORDER BY d.period, d.id;`
+
+	var rows []UserDataRecordDB
+	if err := dbutils.Select(ctx, storage.db, &rows, sqlString, userID, from, to); err != nil {
+		return nil, err
+	}
+
+	res := make([]bottypes.UserDataRecord, 0, len(rows))
+	for _, row := range rows {
+		rec := bottypes.UserDataRecord{UserID: userID, Category: row.Category, Comment: row.Comment, Period: row.Period}
+		var err error
+		if rec.Sum, err = money.Parse(row.Sum, row.Currency); err != nil {
+			return nil, fmt.Errorf("parse sum of record: %w", err)
+		}
+		if row.OriginalSum.Valid && row.OriginalCurrency.Valid {
+			if rec.OriginalSum, err = money.Parse(row.OriginalSum.String, row.OriginalCurrency.String); err != nil {
+				return nil, fmt.Errorf("parse original sum of record: %w", err)
+			}
+		}
+		res = append(res, rec)
+	}
+	return res, nil
+}
+
+// GetUserRecordsPage Получение страницы записей о расходах пользователя за период (всех или одной категории)
+// в порядке убывания суммы или даты. Возвращает также общее количество записей.
+func (storage *UserStorage) GetUserRecordsPage(ctx context.Context, userID int64, q bottypes.RecordsQuery) ([]bottypes.UserDataRecord, int, error) {
+	const sqlString = `
+		SELECT c.name, d.sum, d.currency, d.original_sum, d.original_currency, d.comment, d.period, COUNT(*) OVER () AS total
+		FROM userdata d
+		JOIN usercategories c ON c.id = d.category_id
+		WHERE d.user_id = $1 AND d.period >= $2 AND d.period < $3 AND ($4::BIGINT = 0 OR d.category_id = $4)
+		ORDER BY %s
+		LIMIT $5 OFFSET $6;`
+
+	order := "d.sum DESC, d.period DESC, d.id DESC"
+	if q.Order == bottypes.RecordsOrderDate {
+		order = "d.period DESC, d.id DESC"
+	}
+
+	var rows []UserDataRecordPageDB
+	if err := dbutils.Select(ctx, storage.db, &rows, fmt.Sprintf(sqlString, order), userID, q.From, q.To, q.CategoryID, q.Limit, q.Offset); err != nil {
+		return nil, 0, err
+	}
+
+	total := 0
+	res := make([]bottypes.UserDataRecord, 0, len(rows))
+	for _, row := range rows {
+		rec := bottypes.UserDataRecord{UserID: userID, Category: row.Category, Comment: row.Comment, Period: row.Period}
+		var err error
+		if rec.Sum, err = money.Parse(row.Sum, row.Currency); err != nil {
+			return nil, 0, fmt.Errorf("parse sum of record: %w", err)
+		}
+		if row.OriginalSum.Valid && row.OriginalCurrency.Valid {
+			if rec.OriginalSum, err = money.Parse(row.OriginalSum.String, row.OriginalCurrency.String); err != nil {
+				return nil, 0, fmt.Errorf("parse original sum of record: %w", err)
+			}
+		}
+		total = row.Total
+		res = append(res, rec)
+	}
+	return res, total, nil
+}
+
+// GetUserDataByDate Получение сумм расходов пользователя за период [from, to) по дням или месяцам (unit - day или month)
+// в часовом поясе пользователя.
+func (storage *UserStorage) GetUserDataByDate(ctx context.Context, userID int64, from time.Time, to time.Time, unit string, timezone string) ([]bottypes.UserDataDateRecord, error) {
+	const sqlString = `
+		SELECT date_trunc($4, d.period AT TIME ZONE $5) AS date, SUM(d.sum) AS sum, d.currency
+		FROM userdata d
+		WHERE d.user_id = $1 AND d.period >= $2 AND d.period < $3
+		GROUP BY 1, d.currency
+		ORDER BY 1;`
+
+	var rows []UserDataDateRecordDB
+	if err := dbutils.Select(ctx, storage.db, &rows, sqlString, userID, from, to, unit, timezone); err != nil {
+		return nil, err
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("load timezone %s: %w", timezone, err)
+	}
+
+	res := make([]bottypes.UserDataDateRecord, 0, len(rows))
+	for _, row := range rows {
+		sum, err := money.Parse(row.Sum, row.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("parse sum of date %s: %w", row.Date.Format("2006-01-02"), err)
+		}
+		// Время без часового пояса - начало дня или месяца в часовом поясе пользователя.
+		date := time.Date(row.Date.Year(), row.Date.Month(), row.Date.Day(), 0, 0, 0, 0, loc)
+		res = append(res, bottypes.UserDataDateRecord{Date: date, Sum: sum})
+	}
+	return res, nil
+}
+
+// InsertCategory Добавление категории расходов пользователя (повторное добавление игнорируется).
+func (storage *UserStorage) InsertCategory(ctx context.Context, userID int64, catName string, userName string) error {
+	if _, err := storage.CheckIfUserExistAndAdd(ctx, userID, userName); err != nil {
+		return err
+	}
+
+	_, err := insertCategory(ctx, storage.db, userID, catName)
+	return err
+}
+
+func (storage *UserStorage) GetUserCategories(ctx context.Context, userID int64) ([]string, error) {
+	const sqlString = `SELECT name FROM usercategories WHERE user_id = $1 ORDER BY name;`
+
+	var categories []string
+	if err := dbutils.Select(ctx, storage.db, &categories, sqlString, userID); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (storage *UserStorage) GetUserCurrency(ctx context.Context, userID int64) (string, error) {
+	const sqlString = `SELECT currency FROM users WHERE tg_id = $1;`
+
+	var currencies []string
+	if err := dbutils.Select(ctx, storage.db, &currencies, sqlString, userID); err != nil {
+		return "", err
+	}
+	if len(currencies) == 0 {
+		return storage.defaultCurrency, nil
+	}
+	return currencies[0], nil
+}
+
+func (storage *UserStorage) SetUserCurrency(ctx context.Context, userID int64, currencyName string, userName string) error {
+	if _, err := storage.CheckIfUserExistAndAdd(ctx, userID, userName); err != nil {
+		return err
+	}
+
+	const sqlString = `UPDATE users SET currency = $2 WHERE tg_id = $1;`
+
+	_, err := dbutils.Exec(ctx, storage.db, sqlString, userID, currencyName)
+	return err
+}
+
+// GetUserTimezone Получение часового пояса пользователя (часовой пояс по умолчанию, если пользователь его не задал).
+func (storage *UserStorage) GetUserTimezone(ctx context.Context, userID int64) (string, error) {
+	const sqlString = `SELECT timezone FROM users WHERE tg_id = $1;`
+
+	var timezones []string
+	if err := dbutils.Select(ctx, storage.db, &timezones, sqlString, userID); err != nil {
+		return "", err
+	}
+	if len(timezones) == 0 || timezones[0] == "" {
+		return storage.defaultTimezone, nil
+	}
+	return timezones[0], nil
+}
+
+func (storage *UserStorage) SetUserTimezone(ctx context.Context, userID int64, timezone string, userName string) error {
+	if _, err := storage.CheckIfUserExistAndAdd(ctx, userID, userName); err != nil {
+		return err
+	}
+
+	const sqlString = `UPDATE users SET timezone = $2 WHERE tg_id = $1;`
+
+	_, err := dbutils.Exec(ctx, storage.db, sqlString, userID, timezone)
+	return err
+}
+
+// GetUserLimit Получение ежемесячного бюджета пользователя в основной валюте (0 - без ограничений).
+func (storage *UserStorage) GetUserLimit(ctx context.Context, userID int64) (money.Money, error) {
+	const sqlString = `SELECT limits FROM users WHERE tg_id = $1;`
+
+	var limits []string
+	if err := dbutils.Select(ctx, storage.db, &limits, sqlString, userID); err != nil {
+		return money.Money{}, err
+	}
+	if len(limits) == 0 {
+		return storage.defaultLimits, nil
+	}
+	return money.Parse(limits[0], storage.defaultLimits.Currency())
+}
+
+// SetUserLimit Установка ежемесячного бюджета пользователя в основной валюте.
+func (storage *UserStorage) SetUserLimit(ctx context.Context, userID int64, limit money.Money, userName string) error {
+	if limit.Currency() != storage.defaultLimits.Currency() {
+		return fmt.Errorf("limit in %s is not in main currency %s", limit.Currency(), storage.defaultLimits.Currency())
+	}
+	if _, err := storage.CheckIfUserExistAndAdd(ctx, userID, userName); err != nil {
+		return err
+	}
+
+	const sqlString = `UPDATE users SET limits = $2 WHERE tg_id = $1;`
+
+	_, err := dbutils.Exec(ctx, storage.db, sqlString, userID, limit.String())
+	return err
+}
+
+// insertCategory Добавление категории, если ее нет, и получение ее идентификатора.
+func insertCategory(ctx context.Context, db sqlx.ExtContext, userID int64, catName string) (int64, error) {
+	const sqlString = `
+		INSERT INTO usercategories (user_id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id;`
+
+	var ids []int64
+	if err := dbutils.Select(ctx, db, &ids, sqlString, userID, catName); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("category %s is not saved", catName)
+	}
+	return ids[0], nil
+}
+
+// checkUserLimit Проверка, что сумма расходов за период бюджета вместе с новой суммой превысит бюджет.
+func checkUserLimit(ctx context.Context, db sqlx.ExtContext, userID int64, sum money.Money, limitPeriod timeutils.Period) (bool, error) {
+	const sqlString = `
+		SELECT u.limits::TEXT AS limits, COALESCE(SUM(d.sum), 0)::TEXT AS spent
+		FROM users u
+		LEFT JOIN userdata d ON d.user_id = u.tg_id AND d.period >= $2 AND d.period < $3 AND d.currency = $4
+		WHERE u.tg_id = $1
+		GROUP BY u.limits;`
+
+	row, err := dbutils.GetMap(ctx, db, sqlString, userID, limitPeriod.Start(), limitPeriod.End(), sum.Currency())
+	if err != nil {
+		return false, err
+	}
+
+	limitStr, okLimit := row["limits"].(string)
+	spentStr, okSpent := row["spent"].(string)
+	if !okLimit || !okSpent {
+		return false, errors.New("error in type conversion of the query result")
+	}
+
+	limit, err := money.Parse(limitStr, sum.Currency())
+	if err != nil {
+		return false, err
+	}
+	if !limit.IsPositive() {
+		return false, nil
+	}
+
+	spent, err := money.Parse(spentStr, sum.Currency())
+	if err != nil {
+		return false, err
+	}
+	if spent, err = spent.Add(sum); err != nil {
+		return false, err
+	}
+
+	cmp, err := spent.Cmp(limit)
+	if err != nil {
+		return false, err
+	}
+	return cmp > 0, nil
+}
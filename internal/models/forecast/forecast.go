@@ -0,0 +1,198 @@
+package forecast
+
+// Прогноз расходов до конца месяца по расходам с начала месяца, регулярным платежам и истории расходов.
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/helpers/timeutils"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+const (
+	// HistoryMonths Количество предыдущих месяцев для определения регулярных платежей и среднего расхода в день.
+	HistoryMonths = 3
+	// Вес истории в днях при расчете расхода в день: в начале месяца прогноз опирается в основном на историю.
+	historyWeightDays = 7
+)
+
+// Payment Регулярный платеж: запись с той же категорией и комментарием в каждом из предыдущих месяцев.
+type Payment struct {
+	Category string
+	Comment  string
+	Sum      money.Money // Сумма последнего платежа.
+	Day      int         // День месяца последнего платежа.
+	Paid     bool        // Платеж в текущем месяце уже внесен.
+}
+
+// Forecast Прогноз расходов за месяц. Суммы в валюте записей.
+type Forecast struct {
+	Month      timeutils.Period
+	Spent      money.Money // Расходы с начала месяца.
+	Pending    money.Money // Регулярные платежи, ожидаемые до конца месяца.
+	Total      money.Money // Прогноз расходов за месяц.
+	Limit      money.Money // Бюджет на месяц (0 - без ограничений).
+	SafePerDay money.Money // Сумма, которую можно тратить в день до конца месяца, не превышая бюджет.
+	DaysLeft   int         // Дней до конца месяца, включая текущий.
+	Payments   []Payment
+}
+
+// HasLimit Задан бюджет на месяц.
+func (f Forecast) HasLimit() bool {
+	return f.Limit.IsPositive()
+}
+
+// Over Превышение бюджета по прогнозу (0, если прогноз в пределах бюджета).
+func (f Forecast) Over() money.Money {
+	over, err := f.Total.Sub(f.Limit)
+	if err != nil || !f.HasLimit() || !over.IsPositive() {
+		return money.Zero(f.Total.Currency())
+	}
+	return over
+}
+
+// HistoryStart Начало истории расходов, необходимой для прогноза на месяц month.
+func HistoryStart(month timeutils.Period) time.Time {
+	return month.Start().AddDate(0, -HistoryMonths, 0)
+}
+
+// Build Прогноз расходов за месяц month на момент now по записям recs с HistoryStart(month) до now в валюте currency.
+// Прогноз складывается из расходов с начала месяца, неоплаченных регулярных платежей и прочих расходов
+// до конца месяца по среднему расходу в день (с начала месяца с учетом истории предыдущих месяцев).
+func Build(month timeutils.Period, now time.Time, recs []bottypes.UserDataRecord, limit money.Money, currency string) (Forecast, error) {
+	f := Forecast{Month: month, Spent: money.Zero(currency), Pending: money.Zero(currency), Limit: limit}
+
+	var current, history []bottypes.UserDataRecord
+	for _, rec := range recs {
+		if rec.Sum.Currency() != currency {
+			continue
+		}
+		if month.Contains(rec.Period) {
+			current = append(current, rec)
+		} else if !rec.Period.Before(HistoryStart(month)) && rec.Period.Before(month.Start()) {
+			history = append(history, rec)
+		}
+	}
+
+	f.Payments = recurringPayments(month, history, current)
+	recurring := map[string]bool{}
+	for _, p := range f.Payments {
+		recurring[paymentKey(p.Category, p.Comment)] = true
+		if !p.Paid {
+			var err error
+			if f.Pending, err = f.Pending.Add(p.Sum); err != nil {
+				return Forecast{}, err
+			}
+		}
+	}
+
+	// Прочие (нерегулярные) расходы с начала месяца и в предыдущих месяцах.
+	var variable, historyVariable int64
+	for _, rec := range current {
+		var err error
+		if f.Spent, err = f.Spent.Add(rec.Sum); err != nil {
+			return Forecast{}, err
+		}
+		if !recurring[paymentKey(rec.Category, rec.Comment)] {
+			variable += rec.Sum.Amount()
+		}
+	}
+	historyMonths := map[time.Time]bool{}
+	for _, rec := range history {
+		historyMonths[timeutils.NewMonth(rec.Period, month.Start().Location()).Start()] = true
+		if !recurring[paymentKey(rec.Category, rec.Comment)] {
+			historyVariable += rec.Sum.Amount()
+		}
+	}
+	historyDays := 0
+	for start := range historyMonths {
+		historyDays += timeutils.NewMonth(start, start.Location()).Last().Day()
+	}
+
+	// Прошедшие дни месяца, включая текущий.
+	daysInMonth := month.Last().Day()
+	elapsed := daysInMonth
+	if month.Contains(now) {
+		elapsed = now.In(month.Start().Location()).Day()
+	}
+	f.DaysLeft = daysInMonth - elapsed + 1
+
+	perDay := float64(variable) / float64(elapsed)
+	if historyDays > 0 {
+		historyPerDay := float64(historyVariable) / float64(historyDays)
+		perDay = (float64(variable) + historyPerDay*historyWeightDays) / float64(elapsed+historyWeightDays)
+	}
+	rest := money.New(int64(math.Round(perDay*float64(daysInMonth-elapsed))), currency)
+
+	var err error
+	if f.Total, err = f.Spent.Add(f.Pending); err != nil {
+		return Forecast{}, err
+	}
+	if f.Total, err = f.Total.Add(rest); err != nil {
+		return Forecast{}, err
+	}
+
+	f.SafePerDay = money.Zero(currency)
+	if f.HasLimit() {
+		available := limit.Amount() - f.Spent.Amount() - f.Pending.Amount()
+		if available > 0 {
+			f.SafePerDay = money.New(available/int64(f.DaysLeft), currency)
+		}
+	}
+	return f, nil
+}
+
+// Регулярные платежи: записи с одинаковыми категорией и комментарием в каждом из HistoryMonths предыдущих месяцев.
+func recurringPayments(month timeutils.Period, history []bottypes.UserDataRecord, current []bottypes.UserDataRecord) []Payment {
+	loc := month.Start().Location()
+	months := map[string]map[time.Time]bool{}
+	last := map[string]bottypes.UserDataRecord{}
+	for _, rec := range history {
+		if strings.TrimSpace(rec.Comment) == "" {
+			continue
+		}
+		key := paymentKey(rec.Category, rec.Comment)
+		if months[key] == nil {
+			months[key] = map[time.Time]bool{}
+		}
+		months[key][timeutils.NewMonth(rec.Period, loc).Start()] = true
+		if prev, ok := last[key]; !ok || !rec.Period.Before(prev.Period) {
+			last[key] = rec
+		}
+	}
+
+	paid := map[string]bool{}
+	for _, rec := range current {
+		paid[paymentKey(rec.Category, rec.Comment)] = true
+	}
+
+	var res []Payment
+	for key, recMonths := range months {
+		if len(recMonths) < HistoryMonths {
+			continue
+		}
+		rec := last[key]
+		res = append(res, Payment{
+			Category: rec.Category,
+			Comment:  strings.TrimSpace(rec.Comment),
+			Sum:      rec.Sum,
+			Day:      rec.Period.In(loc).Day(),
+			Paid:     paid[key],
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Day != res[j].Day {
+			return res[i].Day < res[j].Day
+		}
+		return res[i].Comment < res[j].Comment
+	})
+	return res
+}
+
+func paymentKey(category string, comment string) string {
+	return category + "\x1f" + strings.ToLower(strings.TrimSpace(comment))
+}
@@ -0,0 +1,322 @@
+package imports
+
+// Разбор банковских выписок в формате CSV: определение кодировки и разделителя,
+// выделение заголовка и строк операций, преобразование строк в операции по сопоставлению колонок.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Кодировки выписок.
+const (
+	EncodingUTF8        = "utf-8"
+	EncodingWindows1251 = "windows-1251"
+)
+
+// Разделители колонок, из которых выбирается разделитель выписки (в порядке предпочтения).
+var delimiters = []rune{';', ',', '\t', '|'}
+
+// Форматы дат операций в выписках.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"02.01.2006",
+	"02.01.2006 15:04",
+	"02.01.2006 15:04:05",
+	"02.01.06",
+	"02/01/2006",
+	"02-01-2006",
+	time.RFC3339,
+}
+
+var ErrEmptyStatement = errors.New("в выписке не найдены строки с операциями")
+
+// Statement Выписка банка в формате CSV.
+type Statement struct {
+	Encoding  string // EncodingUTF8 или EncodingWindows1251.
+	Delimiter rune
+	Header    []string
+	Rows      [][]string
+}
+
+// ParseCSV Разбор выписки: кодировка UTF-8 или Windows-1251, разделитель ";", ",", табуляция или "|".
+// Заголовком считается первая строка с тем же числом колонок, что и у большинства строк выписки
+// (строки до заголовка, например, реквизиты счета, пропускаются).
+func ParseCSV(data []byte) (Statement, error) {
+	text, encoding := decode(data)
+
+	var (
+		best      [][]string
+		bestDelim rune
+		bestCols  int
+	)
+	for _, delim := range delimiters {
+		records, cols := readRecords(text, delim)
+		if cols < 2 {
+			continue
+		}
+		if count := countRecords(records, cols); count > countRecords(best, bestCols) {
+			best, bestDelim, bestCols = records, delim, cols
+		}
+	}
+	if best == nil {
+		return Statement{}, ErrEmptyStatement
+	}
+
+	st := Statement{Encoding: encoding, Delimiter: bestDelim}
+	for _, rec := range best {
+		if len(rec) != bestCols || isEmptyRecord(rec) {
+			continue
+		}
+		if st.Header == nil {
+			st.Header = trimFields(rec)
+			continue
+		}
+		st.Rows = append(st.Rows, trimFields(rec))
+	}
+	if len(st.Rows) == 0 {
+		return Statement{}, ErrEmptyStatement
+	}
+	return st, nil
+}
+
+// BankKey Признак банка: отпечаток заголовка выписки (выписки одного банка имеют одинаковый заголовок).
+func (st Statement) BankKey() string {
+	header := make([]string, len(st.Header))
+	for i, name := range st.Header {
+		header[i] = strings.ToLower(name)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(header, "\x1f")))
+	return fmt.Sprintf("%x", sum[:8])
+}
+
+// Transactions Операции выписки по сопоставлению колонок. Суммы в валюте currency, даты в часовом поясе loc.
+// Возвращает также количество строк, которые не удалось распознать.
+func (st Statement) Transactions(mapping bottypes.ImportMapping, currency string, loc *time.Location) ([]Transaction, int) {
+	txs := make([]Transaction, 0, len(st.Rows))
+	skipped := 0
+	for _, row := range st.Rows {
+		tx, err := parseRow(row, mapping, currency, loc)
+		if err != nil {
+			skipped++
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	return txs, skipped
+}
+
+// Expenses Расходы из операций выписки с положительной суммой.
+// Если в выписке есть операции с отрицательной суммой, расходами считаются они, а поступления пропускаются;
+// иначе (выписка только по списаниям) расходами считаются все операции.
+func Expenses(txs []Transaction) []Transaction {
+	hasNegative := false
+	for _, tx := range txs {
+		if tx.Amount.IsNegative() {
+			hasNegative = true
+			break
+		}
+	}
+
+	res := make([]Transaction, 0, len(txs))
+	for _, tx := range txs {
+		switch {
+		case tx.Amount.IsZero():
+			continue
+		case tx.Amount.IsNegative():
+			tx.Amount = money.New(-tx.Amount.Amount(), tx.Amount.Currency())
+		case hasNegative:
+			continue
+		}
+		res = append(res, tx)
+	}
+	return res
+}
+
+func parseRow(row []string, mapping bottypes.ImportMapping, currency string, loc *time.Location) (Transaction, error) {
+	if mapping.DateColumn < 0 || mapping.AmountColumn < 0 || mapping.DateColumn >= len(row) || mapping.AmountColumn >= len(row) || mapping.DescriptionColumn >= len(row) {
+		return Transaction{}, errors.New("incorrect column mapping")
+	}
+
+	date, err := ParseDate(row[mapping.DateColumn], loc)
+	if err != nil {
+		return Transaction{}, err
+	}
+	amount, err := ParseAmount(row[mapping.AmountColumn], currency)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	tx := Transaction{Date: date, Amount: amount}
+	if mapping.DescriptionColumn >= 0 {
+		tx.Description = row[mapping.DescriptionColumn]
+	}
+	return tx, nil
+}
+
+// ParseDate Разбор даты операции в одном из распространенных форматов.
+func ParseDate(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if date, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("incorrect date %q", s)
+}
+
+// ParseAmount Разбор суммы операции: "-1 234,56", "1,234.56", "−350.00 BYN", "(350.00)".
+func ParseAmount(s string, currency string) (money.Money, error) {
+	s = strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == ',':
+			sb.WriteRune(r)
+		case r == '-' || r == '−' || r == '–':
+			negative = true
+		}
+	}
+	num := sb.String()
+
+	// Если есть и точка, и запятая, разделителем дробной части считается последний из них.
+	lastDot, lastComma := strings.LastIndex(num, "."), strings.LastIndex(num, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0 && lastDot > lastComma:
+		num = strings.ReplaceAll(num, ",", "")
+	case lastDot >= 0 && lastComma >= 0:
+		num = strings.ReplaceAll(num, ".", "")
+	}
+	if num == "" {
+		return money.Money{}, fmt.Errorf("incorrect amount %q", s)
+	}
+	if negative {
+		num = "-" + num
+	}
+	return money.Parse(num, currency)
+}
+
+// Текст выписки в UTF-8: файлы не в UTF-8 считаются файлами в кодировке Windows-1251.
+func decode(data []byte) (string, string) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if utf8.Valid(data) {
+		return string(data), EncodingUTF8
+	}
+	decoded, err := charmap.Windows1251.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data), EncodingUTF8
+	}
+	return string(decoded), EncodingWindows1251
+}
+
+// Строки выписки с разделителем delim и наиболее частое число колонок в них.
+func readRecords(text string, delim rune) ([][]string, int) {
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comma = delim
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var records [][]string
+	counts := map[int]int{}
+	for {
+		rec, err := r.Read()
+		if err != nil {
+			// Конец файла или строка, которую не удалось разобрать: используются уже прочитанные строки.
+			break
+		}
+		records = append(records, rec)
+		if !isEmptyRecord(rec) {
+			counts[len(rec)]++
+		}
+	}
+
+	cols := 0
+	for n, count := range counts {
+		if count > counts[cols] || (count == counts[cols] && n > cols) {
+			cols = n
+		}
+	}
+	return records, cols
+}
+
+func countRecords(records [][]string, cols int) int {
+	count := 0
+	for _, rec := range records {
+		if len(rec) == cols && !isEmptyRecord(rec) {
+			count++
+		}
+	}
+	return count
+}
+
+func isEmptyRecord(rec []string) bool {
+	for _, field := range rec {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func trimFields(rec []string) []string {
+	res := make([]string, len(rec))
+	for i, field := range rec {
+		res[i] = strings.TrimSpace(field)
+	}
+	return res
+}
+
+// Номер колонки заголовка с одним из названий (без учета регистра) или -1, если колонки нет.
+func columnIndex(header []string, names ...string) int {
+	for i, column := range header {
+		for _, name := range names {
+			if strings.EqualFold(column, name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Значение колонки строки или пустая строка, если колонки нет.
+func field(row []string, column int) string {
+	if column < 0 || column >= len(row) {
+		return ""
+	}
+	return row[column]
+}
+
+// Валюта строки из колонки валюты или валюта currency, если колонки нет или валюта не указана.
+func rowCurrency(row []string, column int, currency string) string {
+	if cur := strings.ToUpper(field(row, column)); len(cur) == 3 {
+		return cur
+	}
+	return currency
+}
+
+// Сумма с заданным знаком: отрицательная для расходов, положительная для поступлений.
+func withSign(amount money.Money, negative bool) money.Money {
+	if amount.IsNegative() != negative && !amount.IsZero() {
+		return money.New(-amount.Amount(), amount.Currency())
+	}
+	return amount
+}
@@ -0,0 +1,88 @@
+package imports
+
+import (
+	"strings"
+
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+// Categorizer Определение категории расхода из выписки по получателю платежа.
+type Categorizer struct {
+	mapping    map[string]string // Категории пользователя по категории из файла в нижнем регистре.
+	payees     map[string]string // Категории последних записей по получателю платежа в нижнем регистре.
+	categories []string          // Категории пользователя.
+	fallback   string            // Категория для нераспознанных расходов.
+}
+
+func NewCategorizer(mapping map[string]string, payees map[string]string, categories []string, fallback string) *Categorizer {
+	return &Categorizer{mapping: mapping, payees: payees, categories: categories, fallback: fallback}
+}
+
+// Category Категория расхода: категория из файла (с учетом сопоставления категорий пользователя),
+// категория прошлых записей с тем же получателем, категория пользователя, название которой
+// встречается в описании, или категория для нераспознанных расходов.
+func (c *Categorizer) Category(tx Transaction) string {
+	if tx.Category != "" {
+		return c.MapCategory(tx.Category)
+	}
+
+	payee := strings.ToLower(strings.TrimSpace(tx.Description))
+	if category, ok := c.payees[payee]; ok && payee != "" {
+		return category
+	}
+
+	best := ""
+	for _, category := range c.categories {
+		if category != c.fallback && len(category) > len(best) && strings.Contains(payee, strings.ToLower(category)) {
+			best = category
+		}
+	}
+	if best != "" {
+		return best
+	}
+	return c.fallback
+}
+
+// MapCategory Категория пользователя для категории из файла: сохраненное сопоставление,
+// существующая категория с тем же названием без учета регистра или категория из файла без изменений.
+func (c *Categorizer) MapCategory(source string) string {
+	if category, ok := c.mapping[strings.ToLower(source)]; ok {
+		return category
+	}
+	for _, category := range c.categories {
+		if strings.EqualFold(category, source) {
+			return category
+		}
+	}
+	return source
+}
+
+// SourceCategories Категории из файла в порядке первого появления.
+func SourceCategories(txs []Transaction) []string {
+	seen := map[string]bool{}
+	var res []string
+	for _, tx := range txs {
+		key := strings.ToLower(tx.Category)
+		if tx.Category == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		res = append(res, tx.Category)
+	}
+	return res
+}
+
+// Records Записи о расходах из расходов выписки (см. Expenses). Суммы в валюте выписки.
+func Records(expenses []Transaction, c *Categorizer) []bottypes.UserDataRecord {
+	recs := make([]bottypes.UserDataRecord, 0, len(expenses))
+	for _, tx := range expenses {
+		recs = append(recs, bottypes.UserDataRecord{
+			Category:   c.Category(tx),
+			Sum:        tx.Amount,
+			Comment:    tx.Description,
+			Period:     tx.Date,
+			ExternalID: tx.ID,
+		})
+	}
+	return recs
+}
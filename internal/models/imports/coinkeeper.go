@@ -0,0 +1,109 @@
+package imports
+
+// Разбор выгрузок CSV приложений учета расходов, похожих на CoinKeeper:
+// у каждой операции есть тип (расход, доход, перевод), счет списания ("Из") и категория или счет зачисления ("В").
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrNotCoinKeeper = errors.New("файл не является выгрузкой CoinKeeper")
+
+// CoinKeeperCSV Импортер выгрузок CSV в формате CoinKeeper. Переводы между счетами пропускаются.
+type CoinKeeperCSV struct{}
+
+// Колонки выгрузки CoinKeeper (-1, если колонки нет).
+type coinKeeperColumns struct {
+	date, kind, to, amount, currency, tags, note int
+}
+
+func (CoinKeeperCSV) Name() string {
+	return "CoinKeeper"
+}
+
+func (CoinKeeperCSV) Detect(fileName string, data []byte) bool {
+	if hasExt(fileName, ".json", ".ofx", ".qfx", ".qif") {
+		return false
+	}
+	st, err := ParseCSV(data)
+	if err != nil {
+		return false
+	}
+	_, ok := coinKeeperMapping(st.Header)
+	return ok
+}
+
+func (CoinKeeperCSV) Parse(data []byte, currency string, loc *time.Location) ([]Transaction, int, error) {
+	st, err := ParseCSV(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	cols, ok := coinKeeperMapping(st.Header)
+	if !ok {
+		return nil, 0, ErrNotCoinKeeper
+	}
+
+	var txs []Transaction
+	skipped := 0
+	for _, row := range st.Rows {
+		var negative bool
+		switch strings.ToLower(row[cols.kind]) {
+		case "expense", "расход":
+			negative = true
+		case "income", "доход":
+		default:
+			// Переводы между счетами не являются ни расходами, ни поступлениями.
+			continue
+		}
+
+		tx, err := coinKeeperTransaction(row, cols, negative, currency, loc)
+		if err != nil {
+			skipped++
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	uniqueIDs(txs)
+	return txs, skipped, nil
+}
+
+func coinKeeperTransaction(row []string, cols coinKeeperColumns, negative bool, currency string, loc *time.Location) (Transaction, error) {
+	date, err := ParseDate(row[cols.date], loc)
+	if err != nil {
+		return Transaction{}, err
+	}
+	amount, err := ParseAmount(row[cols.amount], rowCurrency(row, cols.currency, currency))
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	tx := Transaction{
+		ID:          hashID("coinkeeper", row...),
+		Date:        date,
+		Amount:      withSign(amount, negative),
+		Description: field(row, cols.note),
+	}
+	if tx.Description == "" {
+		tx.Description = field(row, cols.tags)
+	}
+	if negative {
+		tx.Category = row[cols.to]
+	}
+	return tx, nil
+}
+
+func coinKeeperMapping(header []string) (coinKeeperColumns, bool) {
+	cols := coinKeeperColumns{
+		date:     columnIndex(header, "date", "дата", "данные"),
+		kind:     columnIndex(header, "type", "тип"),
+		to:       columnIndex(header, "to", "в", "куда"),
+		amount:   columnIndex(header, "amount", "сумма"),
+		currency: columnIndex(header, "currency", "валюта"),
+		tags:     columnIndex(header, "tags", "метки"),
+		note:     columnIndex(header, "note", "примечание", "комментарий"),
+	}
+	from := columnIndex(header, "from", "из", "откуда")
+	return cols, cols.date >= 0 && cols.kind >= 0 && from >= 0 && cols.to >= 0 && cols.amount >= 0
+}
@@ -0,0 +1,88 @@
+package imports
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/money"
+)
+
+// Transaction Операция выписки. Расходы имеют отрицательную сумму, поступления - положительную.
+type Transaction struct {
+	ID          string // Идентификатор операции для защиты от повторной загрузки (пустой, если не известен).
+	Date        time.Time
+	Amount      money.Money // Сумма в валюте операции.
+	Description string      // Получатель платежа или описание операции.
+	Category    string      // Категория операции, указанная в файле.
+}
+
+// Importer Разбор файлов одного формата: выписок банков или выгрузок других приложений учета расходов.
+type Importer interface {
+	// Name Название формата для пользователя.
+	Name() string
+	// Detect Проверка, что файл в формате импортера.
+	Detect(fileName string, data []byte) bool
+	// Parse Операции файла. Суммы в валюте операции (currency, если в файле валюта не указана), даты в часовом поясе loc.
+	// Возвращает также количество операций, которые не удалось распознать.
+	Parse(data []byte, currency string, loc *time.Location) ([]Transaction, int, error)
+}
+
+// Импортеры в порядке проверки формата файла.
+var importers []Importer
+
+// Register Добавление импортера. Импортеры проверяются в порядке добавления.
+func Register(importer Importer) {
+	importers = append(importers, importer)
+}
+
+func init() {
+	Register(CoinKeeperCSV{})
+	Register(MoneyLoverCSV{})
+	Register(MoneyLoverJSON{})
+	Register(OFX{})
+	Register(QIF{})
+}
+
+// Detect Импортер для файла. Возвращает nil, если формат не распознан:
+// такой файл разбирается как выписка банка CSV с выбором колонок (см. ParseCSV).
+func Detect(fileName string, data []byte) Importer {
+	for _, importer := range importers {
+		if importer.Detect(fileName, data) {
+			return importer
+		}
+	}
+	return nil
+}
+
+func hasExt(fileName string, exts ...string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for _, e := range exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Идентификатор операции из ее полей для форматов без идентификаторов операций.
+func hashID(prefix string, fields ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return fmt.Sprintf("%v:%x", prefix, sum[:16])
+}
+
+// Уникальность идентификаторов одинаковых операций в файле: "id", "id#2", "id#3".
+func uniqueIDs(txs []Transaction) {
+	seen := map[string]int{}
+	for i := range txs {
+		if txs[i].ID == "" {
+			continue
+		}
+		seen[txs[i].ID]++
+		if n := seen[txs[i].ID]; n > 1 {
+			txs[i].ID = fmt.Sprintf("%v#%v", txs[i].ID, n)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package imports
+
+// Разбор выгрузок приложений учета расходов, похожих на Money Lover: CSV с колонками
+// даты, категории, суммы, валюты и кошелька, а также JSON со списком операций.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrNotMoneyLover = errors.New("файл не является выгрузкой Money Lover")
+
+// Типы категорий Money Lover в выгрузке JSON.
+const (
+	moneyLoverIncome  = "1"
+	moneyLoverExpense = "2"
+)
+
+// MoneyLoverCSV Импортер выгрузок CSV в формате Money Lover. Расходы имеют отрицательную сумму.
+type MoneyLoverCSV struct{}
+
+// Колонки выгрузки Money Lover (-1, если колонки нет).
+type moneyLoverColumns struct {
+	id, date, category, amount, currency, note int
+}
+
+func (MoneyLoverCSV) Name() string {
+	return "Money Lover"
+}
+
+func (MoneyLoverCSV) Detect(fileName string, data []byte) bool {
+	if hasExt(fileName, ".json", ".ofx", ".qfx", ".qif") {
+		return false
+	}
+	st, err := ParseCSV(data)
+	if err != nil {
+		return false
+	}
+	_, ok := moneyLoverMapping(st.Header)
+	return ok
+}
+
+func (MoneyLoverCSV) Parse(data []byte, currency string, loc *time.Location) ([]Transaction, int, error) {
+	st, err := ParseCSV(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	cols, ok := moneyLoverMapping(st.Header)
+	if !ok {
+		return nil, 0, ErrNotMoneyLover
+	}
+
+	var txs []Transaction
+	skipped := 0
+	for _, row := range st.Rows {
+		date, err := ParseDate(row[cols.date], loc)
+		if err != nil {
+			skipped++
+			continue
+		}
+		amount, err := ParseAmount(row[cols.amount], rowCurrency(row, cols.currency, currency))
+		if err != nil {
+			skipped++
+			continue
+		}
+
+		tx := Transaction{
+			ID:          hashID("moneylover", row...),
+			Date:        date,
+			Amount:      amount,
+			Description: field(row, cols.note),
+			Category:    row[cols.category],
+		}
+		if id := field(row, cols.id); id != "" {
+			tx.ID = "moneylover:" + id
+		}
+		txs = append(txs, tx)
+	}
+	uniqueIDs(txs)
+	return txs, skipped, nil
+}
+
+func moneyLoverMapping(header []string) (moneyLoverColumns, bool) {
+	cols := moneyLoverColumns{
+		id:       columnIndex(header, "id"),
+		date:     columnIndex(header, "date", "дата"),
+		category: columnIndex(header, "category", "категория"),
+		amount:   columnIndex(header, "amount", "сумма"),
+		currency: columnIndex(header, "currency", "валюта"),
+		note:     columnIndex(header, "note", "заметка", "примечание"),
+	}
+	wallet := columnIndex(header, "wallet", "account", "кошелек", "кошелёк")
+	return cols, cols.date >= 0 && cols.category >= 0 && cols.amount >= 0 && wallet >= 0
+}
+
+// MoneyLoverJSON Импортер выгрузок JSON в формате Money Lover: массив операций или объект
+// с массивом "transactions". Категория операции - строка или объект с названием и типом (1 - доход, 2 - расход).
+type MoneyLoverJSON struct{}
+
+func (MoneyLoverJSON) Name() string {
+	return "Money Lover (JSON)"
+}
+
+func (MoneyLoverJSON) Detect(fileName string, data []byte) bool {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	if !hasExt(fileName, ".json") && !bytes.HasPrefix(trimmed, []byte("{")) && !bytes.HasPrefix(trimmed, []byte("[")) {
+		return false
+	}
+	items, err := moneyLoverItems(data)
+	return err == nil && len(items) > 0
+}
+
+func (MoneyLoverJSON) Parse(data []byte, currency string, loc *time.Location) ([]Transaction, int, error) {
+	items, err := moneyLoverItems(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var txs []Transaction
+	skipped := 0
+	for _, item := range items {
+		tx, err := moneyLoverTransaction(item, currency, loc)
+		if err != nil {
+			skipped++
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	if len(txs) == 0 && skipped == 0 {
+		return nil, 0, ErrEmptyStatement
+	}
+	uniqueIDs(txs)
+	return txs, skipped, nil
+}
+
+func moneyLoverTransaction(item map[string]any, currency string, loc *time.Location) (Transaction, error) {
+	date, err := ParseDate(jsonText(item, "date", "displayDate", "display_date", "created_at"), loc)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	currencyText := jsonText(item, "currency", "currency_code")
+	if cur, ok := item["currency"].(map[string]any); ok {
+		currencyText = jsonText(cur, "code")
+	}
+	if len(currencyText) == 3 {
+		currency = strings.ToUpper(currencyText)
+	}
+	amount, err := ParseAmount(jsonText(item, "amount"), currency)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	tx := Transaction{Date: date, Amount: amount, Description: jsonText(item, "note", "description")}
+	kind := strings.ToLower(jsonText(item, "type"))
+	if category, ok := item["category"].(map[string]any); ok {
+		tx.Category = jsonText(category, "name")
+		if kind == "" {
+			kind = jsonText(category, "type")
+		}
+	} else {
+		tx.Category = jsonText(item, "category")
+	}
+
+	// Суммы в выгрузке JSON положительные, вид операции определяется типом.
+	switch kind {
+	case moneyLoverExpense, "expense":
+		tx.Amount = withSign(amount, true)
+	case moneyLoverIncome, "income":
+		tx.Amount = withSign(amount, false)
+		tx.Category = ""
+	}
+
+	if id := jsonText(item, "id", "_id"); id != "" {
+		tx.ID = "moneylover:" + id
+	} else {
+		tx.ID = hashID("moneylover", date.String(), amount.String(), tx.Category, tx.Description)
+	}
+	return tx, nil
+}
+
+// Операции выгрузки JSON: массив объектов или объект с массивом "transactions" ("data", "items").
+func moneyLoverItems(data []byte) ([]map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	dec.UseNumber()
+	var root any
+	if err := dec.Decode(&root); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotMoneyLover, err)
+	}
+
+	var list []any
+	switch v := root.(type) {
+	case []any:
+		list = v
+	case map[string]any:
+		for _, key := range []string{"transactions", "data", "items"} {
+			if arr, ok := v[key].([]any); ok {
+				list = arr
+				break
+			}
+		}
+	}
+
+	items := make([]map[string]any, 0, len(list))
+	for _, v := range list {
+		if item, ok := v.(map[string]any); ok && item["amount"] != nil {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return nil, ErrNotMoneyLover
+	}
+	return items, nil
+}
+
+// Значение первого найденного поля объекта JSON в виде строки.
+func jsonText(item map[string]any, keys ...string) string {
+	for _, key := range keys {
+		switch v := item[key].(type) {
+		case string:
+			if v != "" {
+				return strings.TrimSpace(v)
+			}
+		case json.Number:
+			return v.String()
+		case bool:
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
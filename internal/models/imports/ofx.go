@@ -0,0 +1,153 @@
+package imports
+
+// Разбор выписок OFX (Open Financial Exchange) версий 1.x (SGML, без закрывающих тегов) и 2.x (XML).
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Тег OFX: открывающий или закрывающий, со значением до следующего тега.
+var ofxTagRegexp = regexp.MustCompile(`<(/?)([A-Za-z0-9.]+)>([^<]*)`)
+
+var ErrNotOFX = errors.New("файл не является выпиской OFX")
+
+// OFX Импортер выписок OFX и QFX.
+type OFX struct{}
+
+func (OFX) Name() string {
+	return "OFX"
+}
+
+func (OFX) Detect(fileName string, data []byte) bool {
+	if hasExt(fileName, ".ofx", ".qfx") {
+		return true
+	}
+	head := bytes.ToUpper(data[:min(len(data), 1024)])
+	return bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>"))
+}
+
+func (OFX) Parse(data []byte, currency string, loc *time.Location) ([]Transaction, int, error) {
+	return ParseOFX(data, currency, loc)
+}
+
+// ParseOFX Разбор выписки OFX. Суммы в валюте выписки (CURDEF) или в валюте currency, если она не указана.
+// Идентификатор операции составляется из номера счета и FITID.
+// Возвращает также количество операций, которые не удалось распознать.
+func ParseOFX(data []byte, currency string, loc *time.Location) ([]Transaction, int, error) {
+	text, _ := decode(data)
+	if !strings.Contains(strings.ToUpper(text), "<OFX>") {
+		return nil, 0, ErrNotOFX
+	}
+
+	var (
+		txs       []Transaction
+		skipped   int
+		trn       map[string]string // Поля текущей операции (STMTTRN).
+		curDef    = currency
+		accountID string
+	)
+	finish := func() {
+		if trn == nil {
+			return
+		}
+		tx, err := ofxTransaction(trn, curDef, accountID, loc)
+		if err != nil {
+			skipped++
+		} else {
+			txs = append(txs, tx)
+		}
+		trn = nil
+	}
+
+	for _, m := range ofxTagRegexp.FindAllStringSubmatch(text, -1) {
+		isClose, tag, value := m[1] == "/", strings.ToUpper(m[2]), strings.TrimSpace(m[3])
+		switch {
+		case tag == "STMTTRN" && !isClose:
+			finish()
+			trn = map[string]string{}
+		case tag == "STMTTRN", tag == "BANKTRANLIST" && isClose:
+			finish()
+		case isClose:
+			continue
+		case tag == "CURDEF" && value != "":
+			curDef = strings.ToUpper(value)
+		case tag == "ACCTID" && trn == nil:
+			accountID = value
+		case trn != nil && value != "":
+			// Значение первого вхождения: NAME операции, а не NAME во вложенном PAYEE.
+			if _, ok := trn[tag]; !ok {
+				trn[tag] = unescapeOFX(value)
+			}
+		}
+	}
+	finish()
+
+	if len(txs) == 0 && skipped == 0 {
+		return nil, 0, ErrEmptyStatement
+	}
+	return txs, skipped, nil
+}
+
+func ofxTransaction(trn map[string]string, currency string, accountID string, loc *time.Location) (Transaction, error) {
+	date, err := parseOFXDate(trn["DTPOSTED"], loc)
+	if err != nil {
+		return Transaction{}, err
+	}
+	amount, err := ParseAmount(trn["TRNAMT"], currency)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	tx := Transaction{Date: date, Amount: amount, Description: trn["NAME"]}
+	if tx.Description == "" {
+		tx.Description = trn["MEMO"]
+	}
+	if fitID := trn["FITID"]; fitID != "" {
+		tx.ID = "ofx:" + accountID + ":" + fitID
+	}
+	return tx, nil
+}
+
+// Разбор даты OFX: "20240301", "20240301120000", "20240301120000.000[-5:EST]".
+// Без указания смещения дата считается датой в часовом поясе loc.
+func parseOFXDate(s string, loc *time.Location) (time.Time, error) {
+	zone := loc
+	if i := strings.Index(s, "["); i >= 0 {
+		offsetText, _, _ := strings.Cut(strings.Trim(s[i+1:], "]"), ":")
+		offset, err := strconv.ParseFloat(offsetText, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("incorrect date offset %q", s)
+		}
+		zone = time.FixedZone("", int(offset*3600))
+		s = s[:i]
+	}
+	s, _, _ = strings.Cut(s, ".")
+
+	layout := "20060102150405"
+	switch {
+	case len(s) >= len(layout):
+		s = s[:len(layout)]
+	case len(s) >= 8:
+		s, layout = s[:8], "20060102"
+	default:
+		return time.Time{}, fmt.Errorf("incorrect date %q", s)
+	}
+
+	date, err := time.ParseInLocation(layout, s, zone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("incorrect date %q: %w", s, err)
+	}
+	return date.In(loc), nil
+}
+
+var ofxEntities = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ")
+
+func unescapeOFX(s string) string {
+	return ofxEntities.Replace(s)
+}
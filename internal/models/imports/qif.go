@@ -0,0 +1,137 @@
+package imports
+
+// Разбор выписок QIF (Quicken Interchange Format).
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Форматы дат QIF (после замены апострофа на "/" и удаления пробелов): "03/01/2024", "3/1'24".
+var qifDateLayouts = []string{"1/2/2006", "1/2/06"}
+
+var ErrNotQIF = errors.New("файл не является выпиской QIF")
+
+// QIF Импортер выписок QIF.
+type QIF struct{}
+
+func (QIF) Name() string {
+	return "QIF"
+}
+
+func (QIF) Detect(fileName string, data []byte) bool {
+	if hasExt(fileName, ".qif") {
+		return true
+	}
+	head := bytes.TrimSpace(bytes.TrimPrefix(data[:min(len(data), 64)], []byte("\ufeff")))
+	return bytes.HasPrefix(bytes.ToUpper(head), []byte("!TYPE:"))
+}
+
+func (QIF) Parse(data []byte, currency string, loc *time.Location) ([]Transaction, int, error) {
+	return ParseQIF(data, currency, loc)
+}
+
+// ParseQIF Разбор выписки QIF со счетами типов Bank, Cash, CCard, Oth A и Oth L (инвестиционные счета не поддерживаются).
+// В QIF нет идентификаторов операций, поэтому идентификатор составляется из полей операции.
+// Возвращает также количество операций, которые не удалось распознать.
+func ParseQIF(data []byte, currency string, loc *time.Location) ([]Transaction, int, error) {
+	text, _ := decode(data)
+	if !strings.HasPrefix(strings.TrimSpace(text), "!") {
+		return nil, 0, ErrNotQIF
+	}
+
+	var (
+		txs            []Transaction
+		skipped        int
+		fields         = map[string]string{}
+		inTransactions bool
+	)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			header := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case header == "!OPTION:AUTOSWITCH", header == "!CLEAR:AUTOSWITCH":
+			case strings.HasPrefix(header, "!TYPE:"):
+				switch strings.TrimSpace(strings.TrimPrefix(header, "!TYPE:")) {
+				case "BANK", "CASH", "CCARD", "OTH A", "OTH L":
+					inTransactions = true
+				default:
+					inTransactions = false
+				}
+			default:
+				inTransactions = false
+			}
+			fields = map[string]string{}
+			continue
+		}
+
+		code, value := line[:1], strings.TrimSpace(line[1:])
+		if code != "^" {
+			// Поля разбиения операции (S, E, $) не используются, повторные поля не перезаписывают первые.
+			if _, ok := fields[code]; !ok {
+				fields[code] = value
+			}
+			continue
+		}
+
+		if inTransactions && len(fields) > 0 {
+			tx, err := qifTransaction(fields, currency, loc)
+			if err != nil {
+				skipped++
+			} else {
+				txs = append(txs, tx)
+			}
+		}
+		fields = map[string]string{}
+	}
+
+	if len(txs) == 0 && skipped == 0 {
+		return nil, 0, ErrEmptyStatement
+	}
+	uniqueIDs(txs)
+	return txs, skipped, nil
+}
+
+func qifTransaction(fields map[string]string, currency string, loc *time.Location) (Transaction, error) {
+	date, err := parseQIFDate(fields["D"], loc)
+	if err != nil {
+		return Transaction{}, err
+	}
+	amountText := fields["T"]
+	if amountText == "" {
+		amountText = fields["U"]
+	}
+	amount, err := ParseAmount(amountText, currency)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	tx := Transaction{Date: date, Amount: amount, Description: fields["P"]}
+	if tx.Description == "" {
+		tx.Description = fields["M"]
+	}
+	// Переводы между счетами ("[Счет]") категорией не считаются, подкатегории ("Еда:Кафе") не используются.
+	if category := fields["L"]; category != "" && !strings.HasPrefix(category, "[") {
+		tx.Category, _, _ = strings.Cut(category, ":")
+	}
+
+	tx.ID = hashID("qif", fields["D"], amountText, fields["P"], fields["M"], fields["N"])
+	return tx, nil
+}
+
+func parseQIFDate(s string, loc *time.Location) (time.Time, error) {
+	normalized := strings.ReplaceAll(strings.ReplaceAll(s, "'", "/"), " ", "")
+	for _, layout := range qifDateLayouts {
+		if date, err := time.ParseInLocation(layout, normalized, loc); err == nil {
+			return date, nil
+		}
+	}
+	return ParseDate(s, loc)
+}
@@ -0,0 +1,141 @@
+package messages
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/charts"
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/helpers/tgfmt"
+	"github.com/shoksin/financesBot/internal/helpers/timeutils"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+	"github.com/shoksin/financesBot/internal/models/reports"
+)
+
+const (
+	txtChartPieTitle = "Расходы по категориям, %v"
+	txtChartBarTitle = "Расходы по %v, %v"
+	txtChartCaption  = "Отчёт за <b>%v</b> (%v)"
+	txtChartError    = "Не удалось построить диаграмму."
+)
+
+// Запрос диаграммы по отчету: "/report_chart pie 20240101-20240331".
+const cmdReportChart = "/report_chart "
+
+// Кнопки выбора диаграммы и сравнения с предыдущим периодом под текстовым отчетом.
+func reportChartButtons(periodKey string) []bottypes.TgRowButtons {
+	return []bottypes.TgRowButtons{{
+		bottypes.TgInlineButton{DisplayName: "Диаграмма по категориям", Value: cmdReportChart + bottypes.ReportKindPie + " " + periodKey},
+		bottypes.TgInlineButton{DisplayName: "График по датам", Value: cmdReportChart + bottypes.ReportKindBar + " " + periodKey},
+	}, {
+		bottypes.TgInlineButton{DisplayName: "Сравнить с предыдущим периодом", Value: cmdReportCompare + " " + periodKey},
+	}}
+}
+
+// Проверка нажатия кнопки выбора диаграммы и постановка запроса в очередь.
+func checkIfReportChartCommand(s *Model, msg Message) (bool, error) {
+	if !msg.IsCallback || !strings.HasPrefix(msg.Text, cmdReportChart) {
+		return false, nil
+	}
+
+	ctx, span := tracer.Start(s.ctx, "checkIfReportChartCommand")
+	s.ctx = ctx
+	defer span.End()
+
+	// [Вид диаграммы], [Ключ периода]
+	args := strings.Fields(strings.TrimPrefix(msg.Text, cmdReportChart))
+	if len(args) != 2 || (args[0] != bottypes.ReportKindPie && args[0] != bottypes.ReportKindBar) {
+		return true, fmt.Errorf("incorrect report chart command %q", msg.Text)
+	}
+
+	period, err := reports.PeriodFromKey(args[1], time.Now().In(getUserLocation(s, msg.UserID)))
+	if err != nil {
+		return true, err
+	}
+	return true, s.tgClient.SendMessage(msg.UserID, getReportByPeriod(s, msg, period, args[0]))
+}
+
+// SendPieChart Отправка круговой диаграммы расходов по категориям за период.
+func (s *Model) SendPieChart(dt []bottypes.UserDataReportRecord, userID int64, period reports.Period, currency string) error {
+	ctx, span := tracer.Start(s.ctx, "SendPieChart")
+	s.ctx = ctx
+	defer span.End()
+
+	if len(dt) == 0 {
+		return s.tgClient.SendMessage(userID, txtReportEmpty)
+	}
+
+	userCurrency := getReportCurrency(s, userID, currency)
+	items := make([]charts.Item, 0, len(dt))
+	for _, rec := range dt {
+		value, err := chartValue(s, rec.Sum, userCurrency)
+		if err != nil {
+			logger.Error("Error currency convertation", "err", err)
+			return s.tgClient.SendMessage(userID, txtChartError)
+		}
+		items = append(items, charts.Item{Label: rec.Category, Value: value})
+	}
+
+	photo, err := charts.PieChart(fmt.Sprintf(txtChartPieTitle, userCurrency), items)
+	if err != nil {
+		logger.Error("Error building pie chart", "err", err)
+		return s.tgClient.SendMessage(userID, txtChartError)
+	}
+	return s.tgClient.SendPhoto(userID, photo, tgfmt.Sprintf(txtChartCaption, period.Name(), userCurrency))
+}
+
+// SendBarChart Отправка графика расходов по дням или месяцам за период.
+func (s *Model) SendBarChart(dt []bottypes.UserDataDateRecord, userID int64, period reports.Period, unit timeutils.Unit, currency string) error {
+	ctx, span := tracer.Start(s.ctx, "SendBarChart")
+	s.ctx = ctx
+	defer span.End()
+
+	if len(dt) == 0 {
+		return s.tgClient.SendMessage(userID, txtReportEmpty)
+	}
+
+	userCurrency := getReportCurrency(s, userID, currency)
+	userLocation := getUserLocation(s, userID)
+
+	// Суммы по началу дня или месяца (в записях могут быть разные валюты за одну дату).
+	sums := map[int64]float64{}
+	for _, rec := range dt {
+		value, err := chartValue(s, rec.Sum, userCurrency)
+		if err != nil {
+			logger.Error("Error currency convertation", "err", err)
+			return s.tgClient.SendMessage(userID, txtChartError)
+		}
+		sums[rec.Date.Unix()] += value
+	}
+
+	unitName, labelLayout := "дням", "02.01"
+	step, _ := timeutils.NewPeriod(unit, period.From, userLocation)
+	if unit == timeutils.Month {
+		unitName, labelLayout = "месяцам", "01.2006"
+	}
+
+	// Даты без расходов отображаются нулевыми столбцами.
+	items := []charts.Item{}
+	for ; step.Start().Before(period.To); step = step.Next() {
+		items = append(items, charts.Item{Label: step.Start().Format(labelLayout), Value: sums[step.Start().Unix()]})
+	}
+
+	photo, err := charts.BarChart(fmt.Sprintf(txtChartBarTitle, unitName, userCurrency), items)
+	if err != nil {
+		logger.Error("Error building bar chart", "err", err)
+		return s.tgClient.SendMessage(userID, txtChartError)
+	}
+	return s.tgClient.SendPhoto(userID, photo, tgfmt.Sprintf(txtChartCaption, period.Name(), userCurrency))
+}
+
+// Сумма в валюте пользователя для диаграммы.
+func chartValue(s *Model, sum money.Money, userCurrency string) (float64, error) {
+	sumCurrency, err := s.currencies.ConvertSumFromBaseToCurrency(userCurrency, sum)
+	if err != nil {
+		return 0, err
+	}
+	value, _ := sumCurrency.Rat().Float64()
+	return value, nil
+}
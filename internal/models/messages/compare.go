@@ -0,0 +1,152 @@
+package messages
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/helpers/tgfmt"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+	"github.com/shoksin/financesBot/internal/models/reports"
+)
+
+const (
+	txtCompareTitle = "Сравнение расходов за <b>%v</b> с расходами за <b>%v</b> (%v)"
+	txtCompareEmpty = "Нет расходов ни в текущем, ни в предыдущем периоде."
+	txtCompareNew   = "новая"
+	txtCompareGone  = "нет расходов"
+)
+
+// Сравнение с предыдущим периодом: "/report_cmp" (этот месяц по сегодняшний день), "/report_cmp этот квартал", "/report_cmp 20240101-20240331".
+const cmdReportCompare = "/report_cmp"
+
+// Строка сравнения расходов по категории.
+type compareRow struct {
+	category string
+	sum      money.Money // Расходы за период (нулевые, если категория исчезла).
+	prevSum  money.Money // Расходы за предыдущий период (нулевые, если категория новая).
+	isNew    bool        // Расходов по категории в предыдущем периоде не было.
+	isGone   bool        // Расходов по категории в периоде нет.
+}
+
+// Постановка в очередь запроса на сравнение расходов за период с предыдущим периодом.
+func compareReport(s *Model, msg Message) error {
+	ctx, span := tracer.Start(s.ctx, "compareReport")
+	s.ctx = ctx
+	defer span.End()
+
+	now := time.Now().In(getUserLocation(s, msg.UserID))
+	text := strings.TrimSpace(strings.TrimPrefix(msg.Text, cmdReportCompare))
+	if text == "" {
+		text = "этот месяц"
+	}
+	period, err := reports.PeriodFromKey(text, now)
+	if err != nil {
+		if period, err = reports.ParsePeriod(text, now); err != nil {
+			return s.tgClient.SendMessage(msg.UserID, txtReportPeriodError+"\n"+txtReportQP)
+		}
+	}
+	// Незавершенный период сравнивается с теми же днями предыдущего месяца, квартала или года.
+	period = period.ToDate(now)
+	return s.tgClient.SendMessage(msg.UserID, getReportByPeriod(s, msg, period, bottypes.ReportKindCompare))
+}
+
+// SendCompareReport Отправка сравнения расходов по категориям за период с предыдущим периодом.
+func (s *Model) SendCompareReport(dt []bottypes.UserDataReportRecord, prevDt []bottypes.UserDataReportRecord, userID int64, period reports.Period, prevPeriod reports.Period, currency string) error {
+	ctx, span := tracer.Start(s.ctx, "SendCompareReport")
+	s.ctx = ctx
+	defer span.End()
+
+	answerText := txtCompareEmpty
+	if len(dt) > 0 || len(prevDt) > 0 {
+		userCurrency := getReportCurrency(s, userID, currency)
+		answerText = tgfmt.Sprintf(txtCompareTitle, period.Name(), prevPeriod.Name(), userCurrency) + "\n" +
+			formatCompareReport(s, dt, prevDt, userCurrency) + getRatesNote(s, userCurrency)
+	}
+	if err := s.tgClient.SendMessage(userID, answerText); err != nil {
+		logger.Error("Error sending message to Telegram", "err", err)
+	}
+	return nil
+}
+
+// Таблица сравнения расходов по категориям: суммы за оба периода, изменение в валюте и в процентах.
+// Категории, по которым расходы есть только в одном из периодов, отмечаются как новые или исчезнувшие.
+func formatCompareReport(s *Model, recs []bottypes.UserDataReportRecord, prevRecs []bottypes.UserDataReportRecord, userCurrency string) string {
+	totalSum, err := convertReportSums(s, recs, userCurrency)
+	if err != nil {
+		return "ошибка конвертации валюты"
+	}
+	prevTotalSum, err := convertReportSums(s, prevRecs, userCurrency)
+	if err != nil {
+		return "ошибка конвертации валюты"
+	}
+
+	prevSums := map[string]money.Money{}
+	for _, rec := range prevRecs {
+		prevSums[rec.Category] = rec.Sum
+	}
+	rows := make([]compareRow, 0, len(recs)+len(prevRecs))
+	seen := map[string]bool{}
+	for _, rec := range recs {
+		prevSum, ok := prevSums[rec.Category]
+		if !ok {
+			prevSum = money.Zero(userCurrency)
+		}
+		rows = append(rows, compareRow{category: rec.Category, sum: rec.Sum, prevSum: prevSum, isNew: !ok})
+		seen[rec.Category] = true
+	}
+	for _, rec := range prevRecs {
+		if !seen[rec.Category] {
+			rows = append(rows, compareRow{category: rec.Category, sum: money.Zero(userCurrency), prevSum: rec.Sum, isGone: true})
+		}
+	}
+
+	total := compareRow{category: "ИТОГО", sum: totalSum, prevSum: prevTotalSum}
+	sumWidth := max(len(totalSum.String()), len(prevTotalSum.String()), len([]rune("Сейчас"))) + 1
+	changes := make([]string, len(rows))
+	changeWidth := len([]rune("Изменение"))
+	for i, row := range rows {
+		changes[i] = formatCompareChange(row)
+		changeWidth = max(changeWidth, len([]rune(changes[i])))
+	}
+	totalChange := formatCompareChange(total)
+	changeWidth = max(changeWidth, len([]rune(totalChange)))
+	line := string(tgfmt.Code(strings.Repeat("-", 2*sumWidth+changeWidth+15))) + "\n"
+
+	var res strings.Builder
+	res.WriteString(string(tgfmt.Code(fmt.Sprintf("%*s | %*s | %*s | %v", sumWidth, "Сейчас", sumWidth, "Было", changeWidth, "Изменение", "Категория"))) + "\n")
+	res.WriteString(line)
+	for i, row := range rows {
+		res.WriteString(string(tgfmt.Code(fmt.Sprintf("%*s | %*s | %*s | %v", sumWidth, row.sum, sumWidth, row.prevSum, changeWidth, changes[i], row.category))) + "\n")
+	}
+	res.WriteString(line)
+	res.WriteString(string(tgfmt.Code(fmt.Sprintf("%*s | %*s | %*s | %v", sumWidth, total.sum, sumWidth, total.prevSum, changeWidth, totalChange, total.category))) + "\n")
+	return res.String()
+}
+
+// Изменение расходов по категории: "+150.00 (+12.50%)", "новая" или "нет расходов".
+func formatCompareChange(row compareRow) string {
+	switch {
+	case row.isNew:
+		return txtCompareNew
+	case row.isGone:
+		return txtCompareGone
+	}
+
+	diff, err := row.sum.Sub(row.prevSum)
+	if err != nil {
+		return "-"
+	}
+	sign := ""
+	if diff.IsPositive() {
+		sign = "+"
+	}
+	if row.prevSum.IsZero() {
+		return sign + diff.String()
+	}
+	percent := new(big.Rat).Mul(new(big.Rat).Quo(diff.Rat(), row.prevSum.Rat()), big.NewRat(100, 1))
+	return fmt.Sprintf("%v%v (%v%v%%)", sign, diff, sign, percent.FloatString(2))
+}
@@ -0,0 +1,225 @@
+package messages
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/tgfmt"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+	"github.com/shoksin/financesBot/internal/models/reports"
+)
+
+const (
+	txtDigests            = "Регулярные отчеты приходят в вашем часовом поясе (%v). Для удаления подписки нажмите на нее.\nСвоя подписка: <code>/digest daily 08:30</code>, <code>/digest weekly пт 18:00</code>, <code>/digest monthly 5 10:00</code> (день месяца от 1 до 28)."
+	txtDigestsEmpty       = "Подписок на регулярные отчеты пока нет. Выберите расписание или задайте свое: <code>/digest daily 08:30</code>, <code>/digest weekly пт 18:00</code>, <code>/digest monthly 5 10:00</code> (день месяца от 1 до 28). Часовой пояс: %v."
+	txtDigestSave         = "Подписка сохранена: %v. Ближайший отчет: %v."
+	txtDigestExists       = "Такая подписка уже есть."
+	txtDigestDelete       = "Подписка на регулярный отчет удалена."
+	txtDigestFormatError  = "Не удалось распознать расписание. Примеры: <code>/digest daily 08:30</code>, <code>/digest weekly пн 09:00</code>, <code>/digest monthly 1 09:00</code>."
+	txtDigestLimitReached = "Достигнуто максимальное количество подписок на регулярные отчеты (%v). Удалите ненужные подписки."
+)
+
+const (
+	// Меню подписок и добавление подписки: "/digest", "/digest weekly пн 09:00".
+	cmdDigest = "/digest"
+	// Удаление подписки: "/digest_del 12".
+	cmdDigestDelete = "/digest_del "
+)
+
+const (
+	// maxUserDigests Максимальное количество подписок на регулярные отчеты у пользователя.
+	maxUserDigests = 5
+	// digestDefaultSendTime Время отправки отчетов с расписанием из меню (09:00).
+	digestDefaultSendTime = 9 * 60
+	// digestDefaultMonthlyDay День месяца ежемесячного отчета из меню.
+	digestDefaultMonthlyDay = 1
+)
+
+var (
+	// Расписание регулярного отчета: "daily 09:00", "weekly пн 09:00", "monthly 1 09:00".
+	digestRegexp = regexp.MustCompile(`^(\S+)(?:\s+(\S+))?\s+(\d{1,2})[:.](\d{2})$`)
+
+	// Дни недели в командах подписки (русские и английские сокращения).
+	digestWeekdays = map[string]time.Weekday{
+		"пн": time.Monday, "вт": time.Tuesday, "ср": time.Wednesday, "чт": time.Thursday, "пт": time.Friday, "сб": time.Saturday, "вс": time.Sunday,
+		"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+	}
+	digestWeekdayNames = map[time.Weekday]string{
+		time.Monday: "понедельникам", time.Tuesday: "вторникам", time.Wednesday: "средам", time.Thursday: "четвергам",
+		time.Friday: "пятницам", time.Saturday: "субботам", time.Sunday: "воскресеньям",
+	}
+)
+
+// Проверка команд управления подписками на регулярные отчеты.
+func checkIfDigestCommand(s *Model, msg Message) (bool, error) {
+	switch {
+	case msg.Text == cmdDigest:
+		return true, showDigests(s, msg.UserID)
+
+	case strings.HasPrefix(msg.Text, cmdDigest+" "):
+		ctx, span := tracer.Start(s.ctx, "checkIfDigestCommand")
+		s.ctx = ctx
+		defer span.End()
+
+		return true, s.tgClient.SendMessage(msg.UserID, addDigest(s, msg))
+
+	case msg.IsCallback && strings.HasPrefix(msg.Text, cmdDigestDelete):
+		digestID, err := strconv.ParseInt(strings.TrimPrefix(msg.Text, cmdDigestDelete), 10, 64)
+		if err != nil {
+			return true, fmt.Errorf("incorrect digest id: %w", err)
+		}
+		if err := s.storage.DeleteDigest(s.ctx, msg.UserID, digestID); err != nil {
+			logger.Error("Error deleting digest", "err", err)
+			return true, fmt.Errorf("delete digest error: %w", err)
+		}
+		return true, s.tgClient.SendMessage(msg.UserID, txtDigestDelete)
+	}
+
+	return false, nil
+}
+
+// Отображение подписок пользователя кнопками для удаления и кнопок добавления подписок с типовым расписанием.
+func showDigests(s *Model, userID int64) error {
+	digests, err := s.storage.GetUserDigests(s.ctx, userID)
+	if err != nil {
+		logger.Error("Error getting digests", "err", err)
+		return fmt.Errorf("get digests error: %w", err)
+	}
+
+	loc := getUserLocation(s, userID)
+	answerText := tgfmt.Sprintf(txtDigests, loc)
+	if len(digests) == 0 {
+		answerText = tgfmt.Sprintf(txtDigestsEmpty, loc)
+	}
+
+	buttons := make([]bottypes.TgRowButtons, 0, len(digests)+3)
+	for _, digest := range digests {
+		buttons = append(buttons, bottypes.TgRowButtons{
+			bottypes.TgInlineButton{DisplayName: "✖ " + formatDigest(digest), Value: fmt.Sprintf("%v%d", cmdDigestDelete, digest.ID)},
+		})
+	}
+	for _, digest := range []bottypes.Digest{
+		{Frequency: bottypes.DigestDaily, SendTime: digestDefaultSendTime},
+		{Frequency: bottypes.DigestWeekly, Weekday: time.Monday, SendTime: digestDefaultSendTime},
+		{Frequency: bottypes.DigestMonthly, Day: digestDefaultMonthlyDay, SendTime: digestDefaultSendTime},
+	} {
+		buttons = append(buttons, bottypes.TgRowButtons{
+			bottypes.TgInlineButton{DisplayName: formatDigest(digest), Value: digestCommand(digest)},
+		})
+	}
+
+	return s.tgClient.ShowInlineButtons(answerText, buttons, userID)
+}
+
+// Разбор и сохранение подписки.
+func addDigest(s *Model, msg Message) string {
+	digest, err := parseDigest(strings.TrimSpace(strings.TrimPrefix(msg.Text, cmdDigest)))
+	if err != nil {
+		return txtDigestFormatError
+	}
+	digest.UserID = msg.UserID
+
+	digests, err := s.storage.GetUserDigests(s.ctx, msg.UserID)
+	if err != nil {
+		logger.Error("Error getting digests", "err", err)
+		return txtReportError
+	}
+	if len(digests) >= maxUserDigests {
+		return tgfmt.Sprintf(txtDigestLimitReached, maxUserDigests)
+	}
+
+	loc := getUserLocation(s, msg.UserID)
+	digest.NextRunAt = reports.NextDigestRun(digest, time.Now(), loc)
+	created, err := s.storage.InsertDigest(s.ctx, digest, msg.UserName)
+	if err != nil {
+		logger.Error("Error saving digest", "err", err)
+		return txtReportError
+	}
+	if !created {
+		return txtDigestExists
+	}
+	return tgfmt.Sprintf(txtDigestSave, formatDigest(digest), digest.NextRunAt.In(loc).Format("02.01.2006 15:04"))
+}
+
+// Перенос времени отправки регулярных отчетов пользователя в новый часовой пояс.
+func rescheduleDigests(s *Model, userID int64, loc *time.Location) {
+	digests, err := s.storage.GetUserDigests(s.ctx, userID)
+	if err != nil {
+		logger.Error("Error getting digests", "err", err)
+		return
+	}
+	for _, digest := range digests {
+		if err := s.storage.SetDigestNextRun(s.ctx, userID, digest.ID, reports.NextDigestRun(digest, time.Now(), loc)); err != nil {
+			logger.Error("Error rescheduling digest", "digestID", digest.ID, "err", err)
+		}
+	}
+}
+
+func parseDigest(text string) (bottypes.Digest, error) {
+	matches := digestRegexp.FindStringSubmatch(strings.ToLower(text))
+	if matches == nil {
+		return bottypes.Digest{}, fmt.Errorf("incorrect digest %q", text)
+	}
+
+	// [всё регулярное выражение], [Периодичность], [День недели или месяца], [Часы], [Минуты]
+	hour, _ := strconv.Atoi(matches[3])
+	minute, _ := strconv.Atoi(matches[4])
+	if hour > 23 || minute > 59 {
+		return bottypes.Digest{}, fmt.Errorf("incorrect digest time %q", text)
+	}
+	digest := bottypes.Digest{SendTime: hour*60 + minute}
+
+	switch matches[1] {
+	case bottypes.DigestDaily, "ежедневно":
+		digest.Frequency = bottypes.DigestDaily
+		if matches[2] != "" {
+			return bottypes.Digest{}, fmt.Errorf("incorrect daily digest %q", text)
+		}
+	case bottypes.DigestWeekly, "еженедельно":
+		weekday, ok := digestWeekdays[matches[2]]
+		if !ok {
+			return bottypes.Digest{}, fmt.Errorf("incorrect digest weekday %q", text)
+		}
+		digest.Frequency = bottypes.DigestWeekly
+		digest.Weekday = weekday
+	case bottypes.DigestMonthly, "ежемесячно":
+		// Дни после 28-го есть не в каждом месяце.
+		day, err := strconv.Atoi(matches[2])
+		if err != nil || day < 1 || day > 28 {
+			return bottypes.Digest{}, fmt.Errorf("incorrect digest day %q", text)
+		}
+		digest.Frequency = bottypes.DigestMonthly
+		digest.Day = day
+	default:
+		return bottypes.Digest{}, fmt.Errorf("incorrect digest frequency %q", text)
+	}
+	return digest, nil
+}
+
+// Команда добавления подписки: "/digest weekly mon 09:00".
+func digestCommand(digest bottypes.Digest) string {
+	sendTime := fmt.Sprintf("%02d:%02d", digest.SendTime/60, digest.SendTime%60)
+	switch digest.Frequency {
+	case bottypes.DigestWeekly:
+		return fmt.Sprintf("%v %v %v %v", cmdDigest, digest.Frequency, strings.ToLower(digest.Weekday.String()[:3]), sendTime)
+	case bottypes.DigestMonthly:
+		return fmt.Sprintf("%v %v %v %v", cmdDigest, digest.Frequency, digest.Day, sendTime)
+	}
+	return fmt.Sprintf("%v %v %v", cmdDigest, digest.Frequency, sendTime)
+}
+
+// Описание подписки: "Ежедневно в 09:00 за вчера", "По понедельникам в 09:00 за прошлую неделю".
+func formatDigest(digest bottypes.Digest) string {
+	sendTime := fmt.Sprintf("%02d:%02d", digest.SendTime/60, digest.SendTime%60)
+	switch digest.Frequency {
+	case bottypes.DigestWeekly:
+		return fmt.Sprintf("По %v в %v за прошлую неделю", digestWeekdayNames[digest.Weekday], sendTime)
+	case bottypes.DigestMonthly:
+		return fmt.Sprintf("%v-го числа в %v за прошлый месяц", digest.Day, sendTime)
+	}
+	return fmt.Sprintf("Ежедневно в %v за вчера", sendTime)
+}
@@ -0,0 +1,101 @@
+package messages
+
+import (
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/export"
+	"github.com/shoksin/financesBot/internal/helpers/tgfmt"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+	"github.com/shoksin/financesBot/internal/models/reports"
+)
+
+const (
+	txtExportChoice  = "За какой период выгрузить расходы? Можно также ввести период командой, например, <code>/export 2024-01-01 2024-03-31</code> или <code>/export 2023</code>."
+	txtExportCaption = "Расходы за <b>%v</b>"
+	txtExportError   = "Не удалось выгрузить расходы."
+)
+
+var btnExportPeriods = []bottypes.TgRowButtons{
+	{bottypes.TgInlineButton{DisplayName: "Неделя", Value: "/export w"}, bottypes.TgInlineButton{DisplayName: "Месяц", Value: "/export m"}, bottypes.TgInlineButton{DisplayName: "Год", Value: "/export y"}},
+	{bottypes.TgInlineButton{DisplayName: "Прошлый месяц", Value: "/export прошлый месяц"}, bottypes.TgInlineButton{DisplayName: "Этот год", Value: "/export этот год"}},
+}
+
+// Колонки выгрузки записей о расходах.
+var exportColumns = []export.Column{
+	{Name: "Дата"},
+	{Name: "Категория"},
+	{Name: "Сумма", Numeric: true},
+	{Name: "Валюта"},
+	{Name: "Исходная сумма", Numeric: true},
+	{Name: "Исходная валюта"},
+	{Name: "Комментарий"},
+}
+
+// Выгрузка записей о расходах за период в файлы CSV и XLSX по команде "/export <период>".
+func exportRecords(s *Model, msg Message) error {
+	ctx, span := tracer.Start(s.ctx, "exportRecords")
+	s.ctx = ctx
+	defer span.End()
+
+	arg := strings.TrimSpace(strings.TrimPrefix(msg.Text, "/export"))
+	if arg == "" {
+		return s.tgClient.ShowInlineButtons(txtExportChoice, btnExportPeriods, msg.UserID)
+	}
+
+	userLocation := getUserLocation(s, msg.UserID)
+	now := time.Now().In(userLocation)
+	period, err := reports.PeriodFromKey(arg, now)
+	if err != nil {
+		if period, err = reports.ParsePeriod(arg, now); err != nil {
+			return s.tgClient.SendMessage(msg.UserID, txtReportPeriodError+"\n"+txtExportChoice)
+		}
+	}
+
+	recs, err := s.storage.GetUserDataRecords(s.ctx, msg.UserID, period.From, period.To)
+	if err != nil {
+		logger.Error("Error getting records for export", "err", err)
+		return s.tgClient.SendMessage(msg.UserID, txtExportError)
+	}
+	if len(recs) == 0 {
+		return s.tgClient.SendMessage(msg.UserID, txtReportEmpty)
+	}
+
+	table := export.Table{Columns: exportColumns, Rows: make([][]string, 0, len(recs))}
+	for _, rec := range recs {
+		// Для записей без исходной суммы используется сумма в основной валюте.
+		originalSum := rec.OriginalSum
+		if originalSum.Currency() == "" {
+			originalSum = rec.Sum
+		}
+		table.Rows = append(table.Rows, []string{
+			rec.Period.In(userLocation).Format("2006-01-02 15:04"),
+			rec.Category,
+			rec.Sum.String(),
+			rec.Sum.Currency(),
+			originalSum.String(),
+			originalSum.Currency(),
+			rec.Comment,
+		})
+	}
+
+	fileName := "expenses_" + period.Key
+	caption := tgfmt.Sprintf(txtExportCaption, period.Name())
+
+	csvData, err := export.CSV(table)
+	if err != nil {
+		logger.Error("Error building csv export", "err", err)
+		return s.tgClient.SendMessage(msg.UserID, txtExportError)
+	}
+	if err := s.tgClient.SendDocument(msg.UserID, fileName+".csv", csvData, caption); err != nil {
+		return err
+	}
+
+	xlsxData, err := export.XLSX(table, "Расходы")
+	if err != nil {
+		logger.Error("Error building xlsx export", "err", err)
+		return s.tgClient.SendMessage(msg.UserID, txtExportError)
+	}
+	return s.tgClient.SendDocument(msg.UserID, fileName+".xlsx", xlsxData, caption)
+}
@@ -0,0 +1,127 @@
+package messages
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/helpers/tgfmt"
+	"github.com/shoksin/financesBot/internal/helpers/timeutils"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/forecast"
+)
+
+const (
+	txtForecast          = "Прогноз расходов за месяц (по %v): <b>%v %v</b>.\nПотрачено с начала месяца: %v %v. Ожидаемые регулярные платежи: %v %v."
+	txtForecastLimit     = "Бюджет: %v %v. Можно тратить в день до конца месяца: <b>%v %v</b> (дней: %v)."
+	txtForecastOver      = "По прогнозу бюджет будет превышен на <b>%v %v</b>."
+	txtForecastInLimit   = "По прогнозу расходы останутся в пределах бюджета."
+	txtForecastNoLimit   = "Бюджет не установлен: /set_limit"
+	txtForecastPayments  = "Регулярные платежи:\n%v"
+	txtForecastShort     = "Прогноз на месяц: %v %v из бюджета %v %v, можно тратить в день: %v %v."
+	txtForecastShortNone = "Прогноз на месяц: %v %v."
+	txtForecastError     = "Не удалось рассчитать прогноз расходов."
+)
+
+// Прогноз расходов за текущий месяц пользователя.
+func getForecast(s *Model, userID int64) (forecast.Forecast, error) {
+	userLocation := getUserLocation(s, userID)
+	now := time.Now().In(userLocation)
+	month := timeutils.NewMonth(now, userLocation)
+
+	recs, err := s.storage.GetUserDataRecords(s.ctx, userID, forecast.HistoryStart(month), month.End())
+	if err != nil {
+		logger.Error("Error getting user data records", "err", err)
+		return forecast.Forecast{}, fmt.Errorf("get user data records error: %w", err)
+	}
+	limit, err := getUserLimit(s, userID)
+	if err != nil {
+		return forecast.Forecast{}, fmt.Errorf("get user limit error: %w", err)
+	}
+	return forecast.Build(month, now, recs, limit, s.currencies.GetMainCurrency())
+}
+
+// Ответ на команду /forecast: прогноз расходов до конца месяца, сравнение с бюджетом и регулярные платежи.
+func getForecastAnswer(s *Model, userID int64) string {
+	ctx, span := tracer.Start(s.ctx, "getForecastAnswer")
+	s.ctx = ctx
+	defer span.End()
+
+	f, err := getForecast(s, userID)
+	if err != nil {
+		logger.Error("Error building forecast", "err", err)
+		return txtForecastError
+	}
+
+	userCurrency := getUserCurrency(s, userID)
+	sums, err := convertForecastSums(s, userCurrency, f.Total, f.Spent, f.Pending, f.Limit, f.SafePerDay, f.Over())
+	if err != nil {
+		logger.Error("Error currency convertation", "err", err)
+		return txtForecastError
+	}
+	total, spent, pending, limit, safePerDay, over := sums[0], sums[1], sums[2], sums[3], sums[4], sums[5]
+
+	lines := []string{tgfmt.Sprintf(txtForecast, f.Month.Last().Format("02.01.2006"), total, userCurrency, spent, userCurrency, pending, userCurrency)}
+	if f.HasLimit() {
+		lines = append(lines, tgfmt.Sprintf(txtForecastLimit, limit, userCurrency, safePerDay, userCurrency, f.DaysLeft))
+		if over.IsPositive() {
+			lines = append(lines, tgfmt.Sprintf(txtForecastOver, over, userCurrency))
+		} else {
+			lines = append(lines, txtForecastInLimit)
+		}
+	} else {
+		lines = append(lines, txtForecastNoLimit)
+	}
+
+	if len(f.Payments) > 0 {
+		var payments strings.Builder
+		for _, p := range f.Payments {
+			sum, err := s.currencies.ConvertSumFromBaseToCurrency(userCurrency, p.Sum)
+			if err != nil {
+				logger.Error("Error currency convertation", "err", err)
+				return txtForecastError
+			}
+			status := fmt.Sprintf("%v числа", p.Day)
+			if p.Paid {
+				status = "оплачен"
+			}
+			payments.WriteString(string(tgfmt.Code(fmt.Sprintf("%v %v %v (%v) - %v", sum, userCurrency, p.Comment, p.Category, status))) + "\n")
+		}
+		lines = append(lines, tgfmt.Sprintf(txtForecastPayments, tgfmt.HTML(payments.String())))
+	}
+	return strings.Join(lines, "\n") + getRatesNote(s, userCurrency)
+}
+
+// Строка прогноза под сообщением о сохранении записи (пустая, если прогноз не удалось рассчитать).
+func getForecastLine(s *Model, userID int64) string {
+	f, err := getForecast(s, userID)
+	if err != nil {
+		logger.Error("Error building forecast", "err", err)
+		return ""
+	}
+
+	userCurrency := getUserCurrency(s, userID)
+	sums, err := convertForecastSums(s, userCurrency, f.Total, f.Limit, f.SafePerDay)
+	if err != nil {
+		logger.Error("Error currency convertation", "err", err)
+		return ""
+	}
+	if !f.HasLimit() {
+		return "\n" + tgfmt.Sprintf(txtForecastShortNone, sums[0], userCurrency)
+	}
+	return "\n" + tgfmt.Sprintf(txtForecastShort, sums[0], userCurrency, sums[1], userCurrency, sums[2], userCurrency)
+}
+
+// Пересчет сумм прогноза из основной валюты в валюту пользователя.
+func convertForecastSums(s *Model, userCurrency string, sums ...money.Money) ([]money.Money, error) {
+	res := make([]money.Money, 0, len(sums))
+	for _, sum := range sums {
+		sumCurrency, err := s.currencies.ConvertSumFromBaseToCurrency(userCurrency, sum)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, sumCurrency)
+	}
+	return res, nil
+}
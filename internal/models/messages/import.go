@@ -0,0 +1,510 @@
+package messages
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/tgfmt"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+	"github.com/shoksin/financesBot/internal/models/imports"
+)
+
+const (
+	txtImportFileError    = "Не удалось прочитать файл. Отправьте выписку банка в формате CSV, OFX или QIF либо выгрузку CoinKeeper или Money Lover."
+	txtImportFormatError  = "Не удалось распознать выписку: %v."
+	txtImportColumn       = "Выписка распознана (кодировка %v, строк: %v). Выберите колонку, в которой указана %v."
+	txtImportExpired      = "Выписка не найдена. Отправьте файл выписки еще раз."
+	txtImportMappingError = "Дата и сумма должны быть в разных колонках. Выберите колонки заново."
+	txtImportPreview      = "Предварительный просмотр (формат: %v). Операций в файле: %v, расходов к загрузке: %v.\nПропущено поступлений: %v, загруженных ранее расходов: %v, нераспознанных строк: %v, в неподдерживаемой валюте: %v.\n%v\nКатегории расходов без категории в файле определяются по получателю платежа, нераспознанные расходы будут загружены в категорию <b>%v</b>. Данные будут сохранены только после нажатия кнопки «Загрузить»."
+	txtImportPreviewRecs  = "Первые расходы:\n%v"
+	txtImportPreviewCats  = "Категории из файла:\n%v"
+	txtImportPreviewCurr  = "Валюты операций: %v\n"
+	txtImportCategory     = "Категория из файла <code>%v</code> (%v из %v). Выберите вашу категорию для расходов этой категории."
+	txtImportNoExpenses   = "В выписке не найдены расходы. Проверьте выбор колонок."
+	txtImportNoNew        = "Все расходы из выписки уже загружены ранее."
+	txtImportDone         = "Загружено расходов: %v из %v."
+	txtImportCancel       = "Загрузка выписки отменена."
+)
+
+// importCategory Категория для расходов, загруженных из выписки.
+const importCategory = "Выписка банка"
+
+const (
+	// maxImportPreviewRecs Количество расходов в предпросмотре выписки.
+	maxImportPreviewRecs = 5
+	// maxImportErrors Количество строк с ошибками в ответе о загрузке выписки.
+	maxImportErrors = 10
+	// maxImportColumnName Длина названия колонки на кнопке выбора колонки.
+	maxImportColumnName = 30
+	// maxImportPreviewCategories Количество категорий из файла в предпросмотре.
+	maxImportPreviewCategories = 10
+	// pendingImportTTL Время хранения загруженной выписки в ожидании подтверждения загрузки.
+	pendingImportTTL = 30 * time.Minute
+)
+
+// Поля выписки, для которых пользователь выбирает колонку.
+const (
+	importFieldDate        = "date"
+	importFieldAmount      = "amount"
+	importFieldDescription = "desc"
+)
+
+const (
+	// Выбор колонки выписки CSV: "/import_col date 0".
+	cmdImportColumn = "/import_col "
+	// Выбор категории пользователя для категории из файла: "/import_cat 2 5" (номер категории из файла, номер категории пользователя).
+	cmdImportCategory = "/import_cat "
+)
+
+var (
+	btnImportRemap = []bottypes.TgRowButtons{
+		{bottypes.TgInlineButton{DisplayName: "Изменить колонки", Value: "/import_remap"}, bottypes.TgInlineButton{DisplayName: "Отмена", Value: "/import_cancel"}},
+	}
+)
+
+// Выписка, ожидающая выбора колонок и подтверждения загрузки.
+type pendingImport struct {
+	importer         imports.Importer       // Импортер файла (nil для выписки банка CSV с выбором колонок).
+	statement        imports.Statement      // Выписка CSV, для которой выбираются колонки.
+	mapping          bottypes.ImportMapping // Сопоставление колонок выписки CSV.
+	transactions     []imports.Transaction  // Операции файла, разобранного импортером.
+	skipped          int                    // Количество нераспознанных операций файла, разобранного импортером.
+	sourceCategories []string               // Категории расходов из файла.
+	userCategories   []string               // Категории пользователя для сопоставления с категориями из файла.
+	loadedAt         time.Time              // Время загрузки файла.
+}
+
+// Загруженная выписка пользователя. Выписка, не подтвержденная в течение pendingImportTTL, удаляется.
+func getPendingImport(s *Model, userID int64) (*pendingImport, bool) {
+	pending, ok := s.pendingImports[userID]
+	if ok && time.Since(pending.loadedAt) > pendingImportTTL {
+		delete(s.pendingImports, userID)
+		return nil, false
+	}
+	return pending, ok
+}
+
+// Удаление выписок, загрузка которых не была подтверждена или отменена.
+func removeExpiredImports(s *Model) {
+	for userID, pending := range s.pendingImports {
+		if time.Since(pending.loadedAt) > pendingImportTTL {
+			delete(s.pendingImports, userID)
+		}
+	}
+}
+
+// Название формата файла для пользователя.
+func (pending *pendingImport) formatName() string {
+	if pending.importer == nil {
+		return "выписка банка CSV"
+	}
+	return pending.importer.Name()
+}
+
+// Расходы выписки, подготовленные к загрузке.
+type importPlan struct {
+	records     []bottypes.UserDataRecord
+	total       int      // Операций в файле.
+	income      int      // Поступлений (не загружаются).
+	duplicates  int      // Расходов, загруженных ранее.
+	skipped     int      // Нераспознанных строк.
+	unsupported int      // Расходов в валюте, для которой нет курса.
+	categories  []string // Сопоставление категорий из файла: "Food → Еда (12)".
+	currencies  []string // Валюты расходов.
+}
+
+// Проверка загрузки файла выписки и команд импорта (выбор колонок, подтверждение, отмена).
+func checkIfImportCommand(s *Model, msg Message) (bool, error) {
+	switch {
+	case msg.Document != nil:
+		ctx, span := tracer.Start(s.ctx, "checkIfImportCommand")
+		s.ctx = ctx
+		defer span.End()
+
+		return true, loadStatement(s, msg)
+
+	case msg.IsCallback && strings.HasPrefix(msg.Text, cmdImportColumn):
+		pending, ok := getPendingImport(s, msg.UserID)
+		if !ok {
+			return true, s.tgClient.SendMessage(msg.UserID, txtImportExpired)
+		}
+		// [Поле выписки], [Номер колонки]
+		args := strings.Fields(strings.TrimPrefix(msg.Text, cmdImportColumn))
+		if len(args) != 2 {
+			return true, fmt.Errorf("incorrect import column command %q", msg.Text)
+		}
+		column, err := strconv.Atoi(args[1])
+		if err != nil || column < -1 || column >= len(pending.statement.Header) {
+			return true, fmt.Errorf("incorrect import column %q", args[1])
+		}
+		return true, setImportColumn(s, msg, pending, args[0], column)
+
+	case msg.IsCallback && msg.Text == "/import_remap":
+		pending, ok := getPendingImport(s, msg.UserID)
+		if !ok || pending.importer != nil {
+			return true, s.tgClient.SendMessage(msg.UserID, txtImportExpired)
+		}
+		return true, askImportColumn(s, msg.UserID, pending, importFieldDate)
+
+	case msg.IsCallback && msg.Text == "/import_cats":
+		pending, ok := getPendingImport(s, msg.UserID)
+		if !ok {
+			return true, s.tgClient.SendMessage(msg.UserID, txtImportExpired)
+		}
+		categories, err := s.storage.GetUserCategories(s.ctx, msg.UserID)
+		if err != nil {
+			logger.Error("Error getting user categories", "err", err)
+			return true, fmt.Errorf("get user categories error: %w", err)
+		}
+		pending.userCategories = categories
+		return true, askImportCategory(s, msg.UserID, pending, 0)
+
+	case msg.IsCallback && strings.HasPrefix(msg.Text, cmdImportCategory):
+		pending, ok := getPendingImport(s, msg.UserID)
+		if !ok {
+			return true, s.tgClient.SendMessage(msg.UserID, txtImportExpired)
+		}
+		// [Номер категории из файла], [Номер категории пользователя или -1, если категория не меняется]
+		args := strings.Fields(strings.TrimPrefix(msg.Text, cmdImportCategory))
+		if len(args) != 2 {
+			return true, fmt.Errorf("incorrect import category command %q", msg.Text)
+		}
+		source, errSource := strconv.Atoi(args[0])
+		target, errTarget := strconv.Atoi(args[1])
+		if errSource != nil || errTarget != nil || source < 0 || source >= len(pending.sourceCategories) || target < -1 || target >= len(pending.userCategories) {
+			return true, fmt.Errorf("incorrect import category command %q", msg.Text)
+		}
+		return true, setImportCategory(s, msg, pending, source, target)
+
+	case msg.IsCallback && msg.Text == "/import_cats_done":
+		pending, ok := getPendingImport(s, msg.UserID)
+		if !ok {
+			return true, s.tgClient.SendMessage(msg.UserID, txtImportExpired)
+		}
+		return true, showImportPreview(s, msg.UserID, pending)
+
+	case msg.IsCallback && msg.Text == "/import_ok":
+		pending, ok := getPendingImport(s, msg.UserID)
+		if !ok {
+			return true, s.tgClient.SendMessage(msg.UserID, txtImportExpired)
+		}
+		delete(s.pendingImports, msg.UserID)
+		return true, s.tgClient.SendMessage(msg.UserID, importStatement(s, msg, pending))
+
+	case msg.IsCallback && msg.Text == "/import_cancel":
+		delete(s.pendingImports, msg.UserID)
+		return true, s.tgClient.SendMessage(msg.UserID, txtImportCancel)
+	}
+
+	return false, nil
+}
+
+// Разбор загруженного файла. Для файлов известных форматов (OFX, QIF, выгрузки других приложений),
+// а также выписок CSV банков с сохраненным сопоставлением колонок сразу показывается предпросмотр.
+func loadStatement(s *Model, msg Message) error {
+	if len(msg.Document.Data) == 0 {
+		return s.tgClient.SendMessage(msg.UserID, txtImportFileError)
+	}
+
+	pending := &pendingImport{importer: imports.Detect(msg.Document.FileName, msg.Document.Data), loadedAt: time.Now()}
+	var err error
+	if pending.importer != nil {
+		pending.transactions, pending.skipped, err = pending.importer.Parse(msg.Document.Data, getUserCurrency(s, msg.UserID), getUserLocation(s, msg.UserID))
+	} else {
+		pending.statement, err = imports.ParseCSV(msg.Document.Data)
+	}
+	if err != nil {
+		logger.Info("Error parsing statement", "file", msg.Document.FileName, "format", pending.formatName(), "err", err)
+		return s.tgClient.SendMessage(msg.UserID, tgfmt.Sprintf(txtImportFormatError, err))
+	}
+	removeExpiredImports(s)
+	s.pendingImports[msg.UserID] = pending
+
+	if pending.importer != nil {
+		pending.sourceCategories = imports.SourceCategories(imports.Expenses(pending.transactions))
+		return showImportPreview(s, msg.UserID, pending)
+	}
+	pending.mapping = bottypes.ImportMapping{BankKey: pending.statement.BankKey(), DateColumn: -1, AmountColumn: -1, DescriptionColumn: -1}
+
+	mapping, found, err := s.storage.GetImportMapping(s.ctx, msg.UserID, pending.mapping.BankKey)
+	if err != nil {
+		logger.Error("Error getting import mapping", "err", err)
+	}
+	if found {
+		pending.mapping = mapping
+		return showImportPreview(s, msg.UserID, pending)
+	}
+	return askImportColumn(s, msg.UserID, pending, importFieldDate)
+}
+
+// Запрос колонки выписки для поля кнопками с названиями колонок.
+func askImportColumn(s *Model, userID int64, pending *pendingImport, field string) error {
+	buttons := []bottypes.TgRowButtons{{}}
+	for i, name := range pending.statement.Header {
+		if i%3 == 0 && i > 0 {
+			buttons = append(buttons, bottypes.TgRowButtons{})
+		}
+		if name == "" {
+			name = fmt.Sprintf("Колонка %v", i+1)
+		}
+		if runes := []rune(name); len(runes) > maxImportColumnName {
+			name = string(runes[:maxImportColumnName-1]) + "…"
+		}
+		buttons[len(buttons)-1] = append(buttons[len(buttons)-1], bottypes.TgInlineButton{DisplayName: name, Value: fmt.Sprintf("%v%v %v", cmdImportColumn, field, i)})
+	}
+
+	fieldName := "дата операции"
+	switch field {
+	case importFieldAmount:
+		fieldName = "сумма операции"
+	case importFieldDescription:
+		fieldName = "описание операции"
+		buttons = append(buttons, bottypes.TgRowButtons{bottypes.TgInlineButton{DisplayName: "Нет описания", Value: fmt.Sprintf("%v%v %v", cmdImportColumn, field, -1)}})
+	}
+
+	text := tgfmt.Sprintf(txtImportColumn, pending.statement.Encoding, len(pending.statement.Rows), fieldName)
+	return s.tgClient.ShowInlineButtons(text, buttons, userID)
+}
+
+// Сохранение выбранной колонки и переход к следующему полю или к предпросмотру.
+func setImportColumn(s *Model, msg Message, pending *pendingImport, field string, column int) error {
+	switch field {
+	case importFieldDate:
+		if column < 0 {
+			return s.tgClient.SendMessage(msg.UserID, txtImportMappingError)
+		}
+		pending.mapping.DateColumn = column
+		return askImportColumn(s, msg.UserID, pending, importFieldAmount)
+	case importFieldAmount:
+		if column < 0 || column == pending.mapping.DateColumn {
+			return s.tgClient.SendMessage(msg.UserID, txtImportMappingError)
+		}
+		pending.mapping.AmountColumn = column
+		return askImportColumn(s, msg.UserID, pending, importFieldDescription)
+	case importFieldDescription:
+		pending.mapping.DescriptionColumn = column
+	default:
+		return fmt.Errorf("unknown import field %q", field)
+	}
+
+	if pending.mapping.DateColumn < 0 || pending.mapping.AmountColumn < 0 {
+		return s.tgClient.SendMessage(msg.UserID, txtImportMappingError)
+	}
+	// Сопоставление сохраняется, чтобы следующие выписки этого банка загружались в одно нажатие.
+	if err := s.storage.SetImportMapping(s.ctx, msg.UserID, pending.mapping, msg.UserName); err != nil {
+		logger.Error("Error saving import mapping", "err", err)
+	}
+	return showImportPreview(s, msg.UserID, pending)
+}
+
+// Подготовка расходов выписки к загрузке: определение категорий (по сопоставлению категорий из файла
+// или по получателю платежа) и исключение расходов, загруженных ранее или в валюте без курса.
+func prepareImport(s *Model, userID int64, pending *pendingImport) importPlan {
+	plan := importPlan{}
+	txs := pending.transactions
+	plan.skipped = pending.skipped
+	if pending.importer == nil {
+		txs, plan.skipped = pending.statement.Transactions(pending.mapping, getUserCurrency(s, userID), getUserLocation(s, userID))
+	}
+	expenses := imports.Expenses(txs)
+	plan.total = len(txs)
+	plan.income = len(txs) - len(expenses)
+
+	categoryMapping, err := s.storage.GetImportCategoryMappings(s.ctx, userID)
+	if err != nil {
+		logger.Error("Error getting import category mappings", "err", err)
+	}
+	payees, err := s.storage.GetPayeeCategories(s.ctx, userID, importCategory)
+	if err != nil {
+		logger.Error("Error getting payee categories", "err", err)
+	}
+	categories, err := s.storage.GetUserCategories(s.ctx, userID)
+	if err != nil {
+		logger.Error("Error getting user categories", "err", err)
+	}
+	categorizer := imports.NewCategorizer(categoryMapping, payees, categories, importCategory)
+	recs := imports.Records(expenses, categorizer)
+
+	ids := make([]string, 0, len(recs))
+	for _, rec := range recs {
+		if rec.ExternalID != "" {
+			ids = append(ids, rec.ExternalID)
+		}
+	}
+	imported := map[string]bool{}
+	if len(ids) > 0 {
+		if imported, err = s.storage.GetImportedExternalIDs(s.ctx, userID, ids); err != nil {
+			logger.Error("Error getting imported transactions", "err", err)
+			imported = map[string]bool{}
+		}
+	}
+
+	supported := map[string]bool{}
+	for _, currency := range s.currencies.GetCurrenciesList() {
+		supported[currency] = true
+	}
+
+	sourceCounts := map[string]int{}
+	currencies := map[string]bool{}
+	for i, rec := range recs {
+		if rec.ExternalID != "" {
+			if imported[rec.ExternalID] {
+				plan.duplicates++
+				continue
+			}
+			// Повтор операции в самом файле.
+			imported[rec.ExternalID] = true
+		}
+		if !supported[rec.Sum.Currency()] {
+			plan.unsupported++
+			continue
+		}
+		if !currencies[rec.Sum.Currency()] {
+			currencies[rec.Sum.Currency()] = true
+			plan.currencies = append(plan.currencies, rec.Sum.Currency())
+		}
+		if expenses[i].Category != "" {
+			sourceCounts[strings.ToLower(expenses[i].Category)]++
+		}
+		plan.records = append(plan.records, rec)
+	}
+
+	for _, source := range pending.sourceCategories {
+		count := sourceCounts[strings.ToLower(source)]
+		if count == 0 {
+			continue
+		}
+		if target := categorizer.MapCategory(source); target != source {
+			plan.categories = append(plan.categories, fmt.Sprintf("%v → %v (%v)", source, target, count))
+		} else {
+			plan.categories = append(plan.categories, fmt.Sprintf("%v (%v)", source, count))
+		}
+	}
+	return plan
+}
+
+// Предпросмотр расходов выписки (без сохранения) с кнопками подтверждения загрузки.
+func showImportPreview(s *Model, userID int64, pending *pendingImport) error {
+	plan := prepareImport(s, userID, pending)
+	if len(plan.records) == 0 {
+		text := txtImportNoExpenses
+		if plan.duplicates > 0 {
+			text = txtImportNoNew
+		}
+		if pending.importer != nil {
+			delete(s.pendingImports, userID)
+			return s.tgClient.SendMessage(userID, text)
+		}
+		return s.tgClient.ShowInlineButtons(text, btnImportRemap, userID)
+	}
+
+	var details strings.Builder
+	details.WriteString(tgfmt.Sprintf(txtImportPreviewRecs, codeLines(plan.records, maxImportPreviewRecs, func(rec bottypes.UserDataRecord) string {
+		return fmt.Sprintf("%v %v %v %v: %v", rec.Period.Format("2006-01-02"), rec.Sum, rec.Sum.Currency(), rec.Category, rec.Comment)
+	})))
+	if len(plan.categories) > 0 {
+		details.WriteString(tgfmt.Sprintf(txtImportPreviewCats, codeLines(plan.categories, maxImportPreviewCategories, func(line string) string { return line })))
+	}
+	if len(plan.currencies) > 1 || plan.currencies[0] != getUserCurrency(s, userID) {
+		details.WriteString(tgfmt.Sprintf(txtImportPreviewCurr, strings.Join(plan.currencies, ", ")))
+	}
+
+	text := tgfmt.Sprintf(txtImportPreview, pending.formatName(), plan.total, len(plan.records), plan.income, plan.duplicates, plan.skipped, plan.unsupported,
+		tgfmt.HTML(details.String()), importCategory)
+	return s.tgClient.ShowInlineButtons(text, importPreviewButtons(pending), userID)
+}
+
+// Кнопки предпросмотра: загрузка, изменение колонок выписки CSV, сопоставление категорий из файла, отмена.
+func importPreviewButtons(pending *pendingImport) []bottypes.TgRowButtons {
+	row := bottypes.TgRowButtons{bottypes.TgInlineButton{DisplayName: "Загрузить", Value: "/import_ok"}}
+	if pending.importer == nil {
+		row = append(row, bottypes.TgInlineButton{DisplayName: "Изменить колонки", Value: "/import_remap"})
+	}
+	if len(pending.sourceCategories) > 0 {
+		row = append(row, bottypes.TgInlineButton{DisplayName: "Сопоставить категории", Value: "/import_cats"})
+	}
+	return []bottypes.TgRowButtons{row, {bottypes.TgInlineButton{DisplayName: "Отмена", Value: "/import_cancel"}}}
+}
+
+// Строки моноширинным шрифтом (не более limit строк).
+func codeLines[T any](items []T, limit int, format func(T) string) tgfmt.HTML {
+	var res strings.Builder
+	for i, item := range items {
+		if i == limit {
+			res.WriteString("...\n")
+			break
+		}
+		res.WriteString(string(tgfmt.Code(format(item))) + "\n")
+	}
+	return tgfmt.HTML(res.String())
+}
+
+// Запрос категории пользователя для категории из файла с номером source.
+func askImportCategory(s *Model, userID int64, pending *pendingImport, source int) error {
+	if source >= len(pending.sourceCategories) {
+		return showImportPreview(s, userID, pending)
+	}
+
+	buttons := []bottypes.TgRowButtons{}
+	for i, category := range pending.userCategories {
+		if i%3 == 0 {
+			buttons = append(buttons, bottypes.TgRowButtons{})
+		}
+		buttons[len(buttons)-1] = append(buttons[len(buttons)-1], bottypes.TgInlineButton{DisplayName: category, Value: fmt.Sprintf("%v%v %v", cmdImportCategory, source, i)})
+	}
+	buttons = append(buttons, bottypes.TgRowButtons{
+		bottypes.TgInlineButton{DisplayName: "Оставить как есть", Value: fmt.Sprintf("%v%v %v", cmdImportCategory, source, -1)},
+		bottypes.TgInlineButton{DisplayName: "Завершить", Value: "/import_cats_done"},
+	})
+
+	text := tgfmt.Sprintf(txtImportCategory, pending.sourceCategories[source], source+1, len(pending.sourceCategories))
+	return s.tgClient.ShowInlineButtons(text, buttons, userID)
+}
+
+// Сохранение категории пользователя для категории из файла и переход к следующей категории.
+// Выбор сохраняется, чтобы в следующих выгрузках категория сопоставлялась автоматически.
+func setImportCategory(s *Model, msg Message, pending *pendingImport, source int, target int) error {
+	sourceCategory := pending.sourceCategories[source]
+	category := sourceCategory
+	if target >= 0 {
+		category = pending.userCategories[target]
+	}
+	if err := s.storage.SetImportCategoryMapping(s.ctx, msg.UserID, sourceCategory, category, msg.UserName); err != nil {
+		logger.Error("Error saving import category mapping", "err", err)
+	}
+	return askImportCategory(s, msg.UserID, pending, source+1)
+}
+
+// Загрузка расходов выписки тем же способом, что и ввод таблицы (/add_tbl).
+func importStatement(s *Model, msg Message, pending *pendingImport) string {
+	ctx, span := tracer.Start(s.ctx, "importStatement")
+	s.ctx = ctx
+	defer span.End()
+
+	userLocation := getUserLocation(s, msg.UserID)
+	plan := prepareImport(s, msg.UserID, pending)
+
+	saved := 0
+	var errorsText strings.Builder
+	errorsCount := 0
+	for i, rec := range plan.records {
+		if txtError := saveImportedRecord(s, msg, rec, userLocation); txtError != "" {
+			if errorsCount < maxImportErrors {
+				errorsText.WriteString(fmt.Sprintf("%v. Ошибка. %v\n", i+1, txtError))
+			}
+			errorsCount++
+			continue
+		}
+		saved++
+	}
+	invalidateUserCache(s, msg.UserID)
+
+	answerText := tgfmt.Sprintf(txtImportDone, saved, len(plan.records))
+	if errorsText.Len() > 0 {
+		answerText += "\n" + errorsText.String()
+	}
+	return answerText + getRatesNote(s, getUserCurrency(s, msg.UserID))
+}
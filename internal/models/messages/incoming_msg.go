@@ -6,48 +6,70 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/big"
 	"regexp"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/helpers/tgfmt"
 	"github.com/shoksin/financesBot/internal/helpers/timeutils"
 	"github.com/shoksin/financesBot/internal/logger"
 	"github.com/shoksin/financesBot/internal/models/bottypes"
+	"github.com/shoksin/financesBot/internal/models/reports"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	txtStart            = "Привет, *%v*. Я помогаю вести учет расходов. Выберите действие."
-	txtUnknownCommand   = "К сожалению, данная команда мне неизвестна. Для начала работы введите /start"
-	txtReportError      = "Не удалось получить данные."
-	txtReportEmpty      = "За указанный период данные отсутствуют."
-	txtReportWait       = "Формирование отчета. Пожалуйста, подождите..."
-	txtCatAdd           = "Введите название категории (не более 30 символов). Для отмены введите 0."
-	txtCatView          = "Выберите категорию, а затем введите сумму."
-	txtCatChoice        = "Выбрана категория *%v*. Введите сумму (только число). Для отмены введите 0. Используемая валюта: *%v*"
-	txtCatSave          = "Категория успешно сохранена."
-	txtCatEmpty         = "Пока нет категорий, сначала добавьте хотя бы одну категорию."
-	txtRecSave          = "Запись успешно сохранена."
-	txtRecOverLimit     = "Запись не сохранена: превышен бюджет раходов в текущем месяце."
-	txtRecTbl           = "Для загрузки истории расходов введите таблицу в следующем формате (дата сумма категория):\n`YYYY-MM-DD 0.00 XXX`\nНапример: \n`2022-09-20 1500 Кино`\n`2022-07-12 350.50 Продукты, еда`\n`2022-08-30 8000 Одежда и обувь`\n`2022-09-01 60 Бензин`\n`2022-09-27 425 Такси`\n`2022-09-26 1500 Бензин`\n`2022-09-26 950 Кошка`\n`2022-09-25 50 Бензин`\nИспользуемая валюта: *%v*"
-	txtReportQP         = "За какой период будем смотреть отчет? Команды периодов: /report_w - неделя, /report_m - месяц, /report_y - год"
-	txtHelp             = "Я - бот, помогающий вести учет расходов. Для начала работы введите /start"
-	txtCurrencyChoice   = "В качестве основной задана валюта: *%v*. Для изменения выберите другую валюту."
-	txtCurrencySet      = "Валюта изменена на *%v*."
-	txtCurrencySetError = "Ошибка сохранения валюты."
-	txtLimitInfo        = "Текущий ежемесячный бюджет: *%v*. Для изменения введите число, например, 80000."
-	txtLimitSet         = "Бюджет изменен на *%v*."
+	txtStart             = "Привет, <b>%v</b>. Я помогаю вести учет расходов. Выберите действие."
+	txtUnknownCommand    = "К сожалению, данная команда мне неизвестна. Для начала работы введите /start"
+	txtReportError       = "Не удалось получить данные."
+	txtReportEmpty       = "За указанный период данные отсутствуют."
+	txtReportTitle       = "Отчёт за <b>%v</b> (%v)"
+	txtReportWait        = "Формирование отчета. Пожалуйста, подождите..."
+	txtReportInProgress  = "Этот отчет уже формируется. Пожалуйста, подождите..."
+	txtReportFailed      = "Не удалось сформировать отчет за %v. Попробуйте запросить отчет еще раз."
+	txtReportStatus      = "Последние запросы отчетов:\n%v"
+	txtReportStatusNone  = "Запросов отчетов пока нет."
+	txtCatAdd            = "Введите название категории (не более 30 символов). Для отмены введите 0."
+	txtCatView           = "Выберите категорию, а затем введите сумму."
+	txtCatChoice         = "Выбрана категория <b>%v</b>. Введите сумму и, при необходимости, комментарий через пробел (например, <code>350.50 обед</code>). Для отмены введите 0. Используемая валюта: <b>%v</b>"
+	txtCatSave           = "Категория успешно сохранена."
+	txtCatEmpty          = "Пока нет категорий, сначала добавьте хотя бы одну категорию."
+	txtRecSave           = "Запись успешно сохранена."
+	txtRecOverLimit      = "Запись не сохранена: превышен бюджет раходов в текущем месяце."
+	txtRecTbl            = "Для загрузки истории расходов введите таблицу в следующем формате (дата сумма категория):\n<code>YYYY-MM-DD 0.00 XXX</code>\nНапример: \n<code>2022-09-20 1500 Кино</code>\n<code>2022-07-12 350.50 Продукты, еда</code>\n<code>2022-08-30 8000 Одежда и обувь</code>\n<code>2022-09-01 60 Бензин</code>\n<code>2022-09-27 425 Такси</code>\n<code>2022-09-26 1500 Бензин</code>\n<code>2022-09-26 950 Кошка</code>\n<code>2022-09-25 50 Бензин</code>\nИспользуемая валюта: <b>%v</b>"
+	txtReportQP          = "За какой период будем смотреть отчет? Команды периодов: /report_w - неделя, /report_m - месяц, /report_y - год.\nПроизвольный период: <code>/report 2024-01-01 2024-03-31</code>, <code>/report 2023</code>, <code>/report прошлый месяц</code>, <code>/report этот квартал</code>.\nСравнение с предыдущим периодом: /report_cmp - этот месяц и прошлый, <code>/report_cmp этот квартал</code>.\nСамые крупные расходы: /report_top - за этот месяц, <code>/report_top прошлый месяц</code>.\nСостояние запросов: /report_status"
+	txtReportPeriodError = "Не удалось распознать период отчета."
+	txtHelp              = "Я - бот, помогающий вести учет расходов. Для начала работы введите /start. Часовой пояс для дат расходов и отчетов: /timezone. Прогноз расходов до конца месяца: /forecast. Регулярные отчеты: /digest. Для загрузки расходов из выписки банка отправьте файл CSV, OFX или QIF. Также можно загрузить выгрузку CoinKeeper или Money Lover (CSV, JSON)."
+	txtCurrencyChoice    = "В качестве основной задана валюта: <b>%v</b>. Для изменения выберите другую валюту."
+	txtCurrencySet       = "Валюта изменена на <b>%v</b>."
+	txtCurrencySetError  = "Ошибка сохранения валюты."
+	txtLimitInfo         = "Текущий ежемесячный бюджет: <b>%v</b>. Для изменения введите число, например, 80000."
+	txtLimitSet          = "Бюджет изменен на <b>%v</b>."
+	txtTimezoneInfo      = "Текущий часовой пояс: <b>%v</b> (сейчас %v). Для изменения введите команду с названием часового пояса, например, <code>/timezone Europe/Moscow</code>."
+	txtTimezoneSet       = "Часовой пояс изменен на <b>%v</b>."
+	txtTimezoneError     = "Неизвестный часовой пояс. Используйте название из базы IANA, например, <code>Europe/Minsk</code>."
+	txtRatesStale        = "<i>Курсы валют по состоянию на %v.</i>"
+	txtRates             = "Курсы валют к <b>%v</b> на %v (изменение со вчера):\n%v"
+	txtRatesError        = "Не удалось получить курсы валют."
+	txtConvertHelp       = "Для конвертации введите команду в формате <code>/convert 100 USD EUR</code>. Доступные валюты: %v"
+	txtConvertResult     = "%v %v = <b>%v %v</b>\nКурс: 1 %v = %v %v"
 )
 
 var btnStart = []bottypes.TgRowButtons{
 	{bottypes.TgInlineButton{DisplayName: "Добавить категорию", Value: "/add_cat"}, bottypes.TgInlineButton{DisplayName: "Добавить расход", Value: "/add_rec"}},
 	{bottypes.TgInlineButton{DisplayName: "Отчёт за неделю", Value: "/report_w"}, bottypes.TgInlineButton{DisplayName: "Отчёт за месяц", Value: "/report_m"}, bottypes.TgInlineButton{DisplayName: "Отчёт за год", Value: "/report_y"}},
-	{bottypes.TgInlineButton{DisplayName: "Ввести данные за прошлый период", Value: "/add_tbl"}},
-	{bottypes.TgInlineButton{DisplayName: "Выбрать валюту", Value: "/choice_currency"}, bottypes.TgInlineButton{DisplayName: "Установить лимит", Value: "/set_limit"}},
+	{bottypes.TgInlineButton{DisplayName: "Ввести данные за прошлый период", Value: "/add_tbl"}, bottypes.TgInlineButton{DisplayName: "Выгрузить расходы", Value: "/export"}},
+	{bottypes.TgInlineButton{DisplayName: "Выбрать валюту", Value: "/choice_currency"}, bottypes.TgInlineButton{DisplayName: "Установить лимит", Value: "/set_limit"}, bottypes.TgInlineButton{DisplayName: "Прогноз", Value: "/forecast"}},
+	{bottypes.TgInlineButton{DisplayName: "Курсы валют", Value: "/rates"}, bottypes.TgInlineButton{DisplayName: "Подписки на курсы", Value: "/rate_alerts"}, bottypes.TgInlineButton{DisplayName: "Регулярные отчеты", Value: "/digest"}},
 }
 
+// maxReportStatusRequests Количество последних запросов отчетов в ответе на /report_status.
+const maxReportStatusRequests = 5
+
 var lineRegexp = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}) (\d+.?\d{0,2}) (.+)$`)
 
 var tracer trace.Tracer
@@ -60,33 +82,60 @@ func init() {
 // MessageSender Интерфейс для работы с сообщениями.
 type MessagesSender interface {
 	SendMessage(userID int64, text string) error
+	SendPhoto(userID int64, photo []byte, caption string) error
+	SendDocument(userID int64, fileName string, data []byte, caption string) error
 	ShowInlineButtons(text string, buttons []bottypes.TgRowButtons, userID int64) error
 }
 
 type UserDataStorage interface {
-	InsertUserDataRecord(ctx context.Context, userID int64, rec bottypes.UserDataRecord, userName string, limitPeriod time.Time) (bool, error)
-	GetUserDataRecord(ctx context.Context, userID int64, period time.Time) ([]bottypes.UserDataReportRecord, error)
+	InsertUserDataRecord(ctx context.Context, userID int64, rec bottypes.UserDataRecord, userName string, limitPeriod timeutils.Period) (bool, error)
+	GetUserDataRecord(ctx context.Context, userID int64, from time.Time, to time.Time) ([]bottypes.UserDataReportRecord, error)
+	GetUserDataRecords(ctx context.Context, userID int64, from time.Time, to time.Time) ([]bottypes.UserDataRecord, error)
+	GetUserRecordsPage(ctx context.Context, userID int64, q bottypes.RecordsQuery) ([]bottypes.UserDataRecord, int, error)
 	InsertCategory(ctx context.Context, userID int64, catName string, userName string) error
 	GetUserCategories(ctx context.Context, userID int64) ([]string, error)
 	GetUserCurrency(ctx context.Context, userID int64) (string, error)
 	SetUserCurrency(ctx context.Context, userID int64, currencyName string, userName string) error
-	GetUserLimit(ctx context.Context, userID int64) (float64, error)
-	SetUserLimit(ctx context.Context, userID int64, limit float64, userName string) error
+	GetUserTimezone(ctx context.Context, userID int64) (string, error)
+	SetUserTimezone(ctx context.Context, userID int64, timezone string, userName string) error
+	GetUserLimit(ctx context.Context, userID int64) (money.Money, error)
+	SetUserLimit(ctx context.Context, userID int64, limit money.Money, userName string) error
+	InsertRateAlert(ctx context.Context, alert bottypes.RateAlert, userName string) error
+	GetUserRateAlerts(ctx context.Context, userID int64) ([]bottypes.RateAlert, error)
+	DeleteRateAlert(ctx context.Context, userID int64, alertID int64) error
+	InsertReportRequest(ctx context.Context, req bottypes.ReportRequestStatus, userName string) (bool, error)
+	FinishReportRequest(ctx context.Context, requestID string, status string, errText string) error
+	GetUserReportRequests(ctx context.Context, userID int64, limit int) ([]bottypes.ReportRequestStatus, error)
+	GetImportMapping(ctx context.Context, userID int64, bankKey string) (bottypes.ImportMapping, bool, error)
+	SetImportMapping(ctx context.Context, userID int64, mapping bottypes.ImportMapping, userName string) error
+	GetImportedExternalIDs(ctx context.Context, userID int64, ids []string) (map[string]bool, error)
+	GetPayeeCategories(ctx context.Context, userID int64, excludeCategory string) (map[string]string, error)
+	GetImportCategoryMappings(ctx context.Context, userID int64) (map[string]string, error)
+	SetImportCategoryMapping(ctx context.Context, userID int64, sourceCategory string, category string, userName string) error
+	InsertDigest(ctx context.Context, digest bottypes.Digest, userName string) (bool, error)
+	GetUserDigests(ctx context.Context, userID int64) ([]bottypes.Digest, error)
+	DeleteDigest(ctx context.Context, userID int64, digestID int64) error
+	SetDigestNextRun(ctx context.Context, userID int64, digestID int64, nextRunAt time.Time) error
 }
 
-// LRUCache Интерфейс для работы с кэшем отчетов.
+// LRUCache Интерфейс для работы с кэшем данных текстовых отчетов (записи хранятся по пользователю и ключу периода).
+// Кэш заполняют обработчики очереди отчетов в процессе бота (см. reports.ReportCache).
 type LRUCache interface {
-	Add(key string, value any)
-	Get(key string) any
+	Get(userID int64, key string) any
+	RemoveUser(userID int64)
 }
 
 // ExchangeRates Интерфейс для работы с курсами валют.
 type ExchangeRates interface {
-	ConvertSumFromBaseToCurrency(currencyName string, sum float64) (float64, error)
-	ConvertSumFromCurrencyToBase(currencyName string, sum float64) (float64, error)
-	GetExchangeRate(currencyName string) (float64, error)
+	ConvertSumFromBaseToCurrency(currencyName string, sum money.Money) (money.Money, error)
+	ConvertSumFromCurrencyToBase(sum money.Money) (money.Money, error)
+	GetExchangeRate(currencyName string) (money.Rate, error)
 	GetMainCurrency() string
 	GetCurrenciesList() []string
+	GetRatesUpdateTime() time.Time
+	IsRatesStale() bool
+	GetRatesDate() time.Time
+	GetExchangeRatesOnDate(ctx context.Context, date time.Time) (bottypes.ExchangeRate, error)
 }
 
 // kafkaProducer Интерфейс для отправки сообщений в кафку.
@@ -105,6 +154,7 @@ type Model struct {
 	kafkaProducer   kafkaProducer
 	lastUserCat     map[int64]string
 	lastUserCommand map[int64]string
+	pendingImports  map[int64]*pendingImport // Загруженные выписки, ожидающие подтверждения импорта.
 }
 
 func New(ctx context.Context, tgClient MessagesSender, storage UserDataStorage, currencies ExchangeRates, reportCache LRUCache, kafka kafkaProducer) *Model {
@@ -117,6 +167,7 @@ func New(ctx context.Context, tgClient MessagesSender, storage UserDataStorage,
 		kafkaProducer:   kafka,
 		lastUserCat:     map[int64]string{},
 		lastUserCommand: map[int64]string{},
+		pendingImports:  map[int64]*pendingImport{},
 	}
 }
 
@@ -127,6 +178,13 @@ type Message struct {
 	UserDisplayName string
 	IsCallback      bool
 	CallbackMsgID   string
+	Document        *Document // Файл, отправленный пользователем.
+}
+
+// Document Файл, отправленный пользователем боту.
+type Document struct {
+	FileName string
+	Data     []byte
 }
 
 func (s *Model) GetCtx() context.Context {
@@ -148,6 +206,11 @@ func (s *Model) IncomingMessage(msg Message) error {
 	s.lastUserCat[msg.UserID] = ""
 	s.lastUserCommand[msg.UserID] = ""
 
+	// Проверка загрузки выписки и команд импорта.
+	if isNeedReturn, err := checkIfImportCommand(s, msg); err != nil || isNeedReturn {
+		return err
+	}
+
 	// Проверка ввода суммы расхода по выбранной категории и сохранение, если введено.
 	if isNeedReturn, err := checkIfEnterCategorySum(s, msg, lastUserCat); err != nil || isNeedReturn {
 		return err
@@ -178,6 +241,26 @@ func (s *Model) IncomingMessage(msg Message) error {
 		return err
 	}
 
+	// Проверка запроса диаграммы по отчету.
+	if isNeedReturn, err := checkIfReportChartCommand(s, msg); err != nil || isNeedReturn {
+		return err
+	}
+
+	// Проверка запроса записей категории из отчета.
+	if isNeedReturn, err := checkIfRecordsCommand(s, msg); err != nil || isNeedReturn {
+		return err
+	}
+
+	// Проверка команд управления подписками на регулярные отчеты.
+	if isNeedReturn, err := checkIfDigestCommand(s, msg); err != nil || isNeedReturn {
+		return err
+	}
+
+	// Проверка команд управления подписками на курсы валют.
+	if isNeedReturn, err := checkIfRateAlertCommand(s, msg, lastUserCommand); err != nil || isNeedReturn {
+		return err
+	}
+
 	// Распознавание стандартных команд.
 	if isNeedReturn, err := checkBotCommands(s, msg); err != nil || isNeedReturn {
 		return err
@@ -187,42 +270,38 @@ func (s *Model) IncomingMessage(msg Message) error {
 
 }
 
-// SendReportToUser Отправка отчета за период.
-func (s *Model) SendReportToUser(dt []bottypes.UserDataReportRecord, userID int64, reportKey string) error {
+// SendReportToUser Отправка отчета за период в валюте currency (пустая - текущая валюта пользователя).
+func (s *Model) SendReportToUser(dt []bottypes.UserDataReportRecord, userID int64, period reports.Period, currency string) error {
 	ctx, span := tracer.Start(s.ctx, "SendReportToUser")
 	s.ctx = ctx
 	defer span.End()
 
-	strReportTitle := "Отчёт за "
-	switch reportKey {
-	case "w":
-		strReportTitle += "*последнюю неделю"
-	case "m":
-		strReportTitle += "*последний месяц"
-	case "y":
-		strReportTitle += "*последний год"
-	}
-
-	// Получение данных из БД.
-	userCurrency := getUserCurrency(s, userID)
+	userCurrency := getReportCurrency(s, userID, currency)
 	answerText := formatReport(s, dt, userCurrency)
 	if len(answerText) == 0 {
 		answerText = txtReportEmpty
 	} else {
-		answerText = fmt.Sprintln(strReportTitle+" ("+userCurrency+")") + answerText
+		answerText = tgfmt.Sprintf(txtReportTitle, period.Name(), userCurrency) + "\n" + answerText + getRatesNote(s, userCurrency)
 	}
 
-	//Save in cache
-
-	reportCacheKey := strconv.Itoa(int(userID)) + reportKey
-	s.reportCache.Add(reportCacheKey, answerText)
-	err := s.tgClient.SendMessage(userID, answerText)
+	var err error
+	if len(dt) > 0 {
+		// Кнопки для построения диаграмм и просмотра записей по тому же периоду.
+		err = s.tgClient.ShowInlineButtons(answerText, append(reportChartButtons(period.Key), reportRecordsButtons(period.Key, dt)...), userID)
+	} else {
+		err = s.tgClient.SendMessage(userID, answerText)
+	}
 	if err != nil {
 		logger.Error("Error sending message to Telegram", "err", err)
 	}
 	return nil
 }
 
+// SendReportError Сообщение о том, что отчет за период сформировать не удалось.
+func (s *Model) SendReportError(userID int64, period reports.Period) error {
+	return s.tgClient.SendMessage(userID, tgfmt.Sprintf(txtReportFailed, period.Name()))
+}
+
 // Область "Внешний интерфейс": конец.
 
 // Область "Служебные функции": начало.
@@ -236,14 +315,28 @@ func checkIfEnterCategorySum(s *Model, msg Message, lastUserCat string) (bool, e
 		s.ctx = ctx
 		defer span.End()
 
-		// Парсинг и конвертация введенной суммы.
-		catSum, err := parseAndConvertSumFromCurrency(s, msg.UserID, msg.Text)
+		// Сумма и необязательный комментарий через пробел: "350.50 обед с коллегами".
+		sumText, comment, _ := strings.Cut(strings.TrimSpace(msg.Text), " ")
+		originalSum, err := money.Parse(sumText, getUserCurrency(s, msg.UserID))
 		if err != nil {
-			return true, err
+			return true, fmt.Errorf("error parse sum: %w", err)
+		}
+		// Конвертация введенной суммы.
+		catSum, err := convertSumFromCurrency(s, originalSum)
+		if err != nil {
+			return true, fmt.Errorf("error currency convertation: %w", err)
 		}
 
-		newRec := bottypes.UserDataRecord{UserID: msg.UserID, Category: lastUserCat, Sum: catSum, Period: time.Now()}
-		isOverLimit, err := s.storage.InsertUserDataRecord(s.ctx, msg.UserID, newRec, msg.UserName, timeutils.BeginOfMonth(newRec.Period))
+		userLocation := getUserLocation(s, msg.UserID)
+		newRec := bottypes.UserDataRecord{
+			UserID:      msg.UserID,
+			Category:    lastUserCat,
+			Sum:         catSum,
+			OriginalSum: originalSum,
+			Comment:     strings.TrimSpace(comment),
+			Period:      time.Now().In(userLocation),
+		}
+		isOverLimit, err := s.storage.InsertUserDataRecord(s.ctx, msg.UserID, newRec, msg.UserName, timeutils.NewMonth(newRec.Period, userLocation))
 		if err != nil {
 			if isOverLimit {
 				return true, s.tgClient.SendMessage(msg.UserID, txtRecOverLimit)
@@ -252,8 +345,9 @@ func checkIfEnterCategorySum(s *Model, msg Message, lastUserCat string) (bool, e
 				return true, fmt.Errorf("insert data record error: %w", err)
 			}
 		}
+		invalidateUserCache(s, msg.UserID)
 		// Ответ пользователю об успешном сохранении.
-		return true, s.tgClient.SendMessage(msg.UserID, txtRecSave)
+		return true, s.tgClient.SendMessage(msg.UserID, txtRecSave+getForecastLine(s, msg.UserID)+getRatesNote(s, getUserCurrency(s, msg.UserID)))
 
 	}
 
@@ -276,6 +370,7 @@ func checkIfEnterNewCategory(s *Model, msg Message, lastUserCommand string) (boo
 				logger.Error("Error saving category", "err", err)
 				return true, fmt.Errorf("insert category error: %w", err)
 			}
+			invalidateUserCache(s, msg.UserID)
 			//Ответ пользователю об успещном сохранении
 			return true, s.tgClient.SendMessage(msg.UserID, txtCatSave)
 		}
@@ -294,14 +389,15 @@ func checkIfEnterNewLimit(s *Model, msg Message, lastUserCommand string) (bool,
 			return true, err
 		}
 
-		if limit >= 0 {
+		if !limit.IsNegative() {
 			err := s.storage.SetUserLimit(ctx, msg.UserID, limit, msg.UserName)
 			if err != nil {
 				logger.Error("Error set currency", "err", err)
 				return true, fmt.Errorf("set currency error: %w", err)
 			}
+			invalidateUserCache(s, msg.UserID)
 			// Ответ пользователю об успешном сохранении.
-			return true, s.tgClient.SendMessage(msg.UserID, fmt.Sprintf(txtLimitSet, msg.Text))
+			return true, s.tgClient.SendMessage(msg.UserID, tgfmt.Sprintf(txtLimitSet, msg.Text)+getRatesNote(s, getUserCurrency(s, msg.UserID)))
 		}
 	}
 	// Это не ввод бюджета.
@@ -321,52 +417,21 @@ func checkIfEnterTableData(s *Model, msg Message, lastUserCommand string) (bool,
 		} else {
 
 			lines := strings.Split(msg.Text, "\n")
+			userLocation := getUserLocation(s, msg.UserID)
 
 			for i, line := range lines {
-				isError := false
-				txtError := ""
-
-				rec, err := parseLineRec(line)
-
+				rec, err := parseLineRec(line, getUserCurrency(s, msg.UserID), userLocation)
 				if err != nil {
-					isError = true
-					txtError = "Ошибка распонавания формата строки."
-				} else {
-					// Сохранение данных.
-					if err := s.storage.InsertCategory(s.ctx, msg.UserID, rec.Category, msg.UserName); err != nil {
-						isError = true
-						txtError = "Ошибка добавления категории."
-
-					} else {
-						rec.UserID = msg.UserID
-
-						//Конвертация из валюты пользователя в базовую.
-						if sum, err := convertSumFromCurrency(s, msg.UserID, rec.Sum); err != nil {
-							isError = true
-							txtError = "Ошибка конвертации валюты."
-
-						} else {
-							rec.Sum = sum
-							//Сохранение записи
-							if isOverLimit, err := s.storage.InsertUserDataRecord(s.ctx, msg.UserID, rec, msg.UserName, timeutils.BeginOfMonth(rec.Period)); err != nil {
-								isError = true
-								if isOverLimit {
-									txtError = "Превышение бюджета."
-								} else {
-									txtError = "Ошибка сохранения записи."
-								}
-							}
-						}
-					}
+					answerText += fmt.Sprintf("%v. Ошибка. %v\n", i+1, "Ошибка распонавания формата строки.")
+					continue
 				}
-				if isError {
-					answerText += fmt.Sprintf("%v. Ошибка. %v", i+1, txtError)
-				} else {
-
+				if txtError := saveImportedRecord(s, msg, rec, userLocation); txtError != "" {
+					answerText += fmt.Sprintf("%v. Ошибка. %v\n", i+1, txtError)
 				}
 			}
+			invalidateUserCache(s, msg.UserID)
 			// Ответ пользователю об сохранении.
-			answerText = txtRecSave + "\n" + answerText
+			answerText = txtRecSave + getForecastLine(s, msg.UserID) + "\n" + answerText + getRatesNote(s, getUserCurrency(s, msg.UserID))
 			return true, s.tgClient.SendMessage(msg.UserID, answerText)
 		}
 
@@ -375,6 +440,34 @@ func checkIfEnterTableData(s *Model, msg Message, lastUserCommand string) (bool,
 	return false, nil
 }
 
+// Сохранение записи, загруженной из таблицы или выписки (сумма в валюте пользователя).
+// Возвращает текст ошибки для пользователя или пустую строку, если запись сохранена.
+// Кэш отчетов сбрасывается один раз после загрузки всех записей.
+func saveImportedRecord(s *Model, msg Message, rec bottypes.UserDataRecord, userLocation *time.Location) string {
+	if err := s.storage.InsertCategory(s.ctx, msg.UserID, rec.Category, msg.UserName); err != nil {
+		return "Ошибка добавления категории."
+	}
+	rec.UserID = msg.UserID
+
+	//Конвертация из валюты пользователя в базовую.
+	sum, err := convertSumFromCurrency(s, rec.Sum)
+	if err != nil {
+		return "Ошибка конвертации валюты."
+	}
+	rec.OriginalSum = rec.Sum
+	rec.Sum = sum
+
+	//Сохранение записи
+	if isOverLimit, err := s.storage.InsertUserDataRecord(s.ctx, msg.UserID, rec, msg.UserName, timeutils.NewMonth(rec.Period, userLocation)); err != nil {
+		if isOverLimit {
+			return "Превышение бюджета."
+		}
+		logger.Error("Error saving record", "err", err)
+		return "Ошибка сохранения записи."
+	}
+	return ""
+}
+
 func checkIfChoiceCategory(s *Model, msg Message) (bool, error) {
 	if msg.IsCallback {
 		if strings.Contains(msg.Text, "/cat") {
@@ -383,7 +476,7 @@ func checkIfChoiceCategory(s *Model, msg Message) (bool, error) {
 			defer span.End()
 
 			cat := strings.Replace(msg.Text, "/cat ", "", -1)
-			answerText := fmt.Sprintf(txtCatChoice, cat, getUserCurrency(s, msg.UserID))
+			answerText := tgfmt.Sprintf(txtCatChoice, cat, getUserCurrency(s, msg.UserID))
 			s.lastUserCat[msg.UserID] = cat
 			return true, s.tgClient.SendMessage(msg.UserID, answerText)
 		}
@@ -399,11 +492,12 @@ func checkIfChoiceCurrency(s *Model, msg Message) (bool, error) {
 			defer span.End()
 
 			choice := strings.Replace(msg.Text, "/curr ", "", -1)
-			answerText := fmt.Sprintf(txtCurrencySet, choice)
+			answerText := tgfmt.Sprintf(txtCurrencySet, choice)
 
 			if err := s.storage.SetUserCurrency(s.ctx, msg.UserID, choice, msg.UserName); err != nil {
 				return true, s.tgClient.SendMessage(msg.UserID, txtCurrencySetError)
 			} else {
+				invalidateUserCache(s, msg.UserID)
 				return true, s.tgClient.SendMessage(msg.UserID, answerText)
 			}
 		}
@@ -417,25 +511,59 @@ func checkBotCommands(s *Model, msg Message) (bool, error) {
 	s.ctx = ctx
 	defer span.End()
 
+	// Команды с параметрами.
+	if msg.Text == "/convert" || strings.HasPrefix(msg.Text, "/convert ") {
+		return true, s.tgClient.SendMessage(msg.UserID, getConvertAnswer(s, msg))
+	}
+	if msg.Text == "/export" || strings.HasPrefix(msg.Text, "/export ") {
+		return true, exportRecords(s, msg)
+	}
+	if msg.Text == "/timezone" || strings.HasPrefix(msg.Text, "/timezone ") {
+		return true, s.tgClient.SendMessage(msg.UserID, getTimezoneAnswer(s, msg))
+	}
+	if msg.Text == cmdReportTop || strings.HasPrefix(msg.Text, cmdReportTop+" ") {
+		return true, topRecords(s, msg)
+	}
+	if msg.Text == cmdReportCompare || strings.HasPrefix(msg.Text, cmdReportCompare+" ") {
+		return true, compareReport(s, msg)
+	}
+	if strings.HasPrefix(msg.Text, "/report ") {
+		period, err := reports.ParsePeriod(strings.TrimPrefix(msg.Text, "/report "), time.Now().In(getUserLocation(s, msg.UserID)))
+		if err != nil {
+			return true, s.tgClient.SendMessage(msg.UserID, txtReportPeriodError+"\n"+txtReportQP)
+		}
+		return true, sendReportByPeriod(s, msg, period)
+	}
+
 	switch msg.Text {
 	case "/start":
 		displayName := msg.UserDisplayName
 		if len(displayName) == 0 {
 			displayName = msg.UserName
 		}
-		return true, s.tgClient.ShowInlineButtons(fmt.Sprintf(txtStart, displayName), btnStart, msg.UserID)
+		return true, s.tgClient.ShowInlineButtons(tgfmt.Sprintf(txtStart, displayName), btnStart, msg.UserID)
 
 	case "/report":
 		return true, s.tgClient.SendMessage(msg.UserID, txtReportQP)
+	case "/forecast":
+		return true, s.tgClient.SendMessage(msg.UserID, getForecastAnswer(s, msg.UserID))
+	case "/rates":
+		return true, s.tgClient.SendMessage(msg.UserID, getRatesAnswer(s, msg.UserID))
 	case "/help":
 		return true, s.tgClient.SendMessage(msg.UserID, txtHelp)
 	case "/add_tbl":
 		s.lastUserCommand[msg.UserID] = "/add_tbl"
 		userCurrency := getUserCurrency(s, msg.UserID)
-		return true, s.tgClient.SendMessage(msg.UserID, fmt.Sprintf(txtRecTbl, userCurrency))
+		return true, s.tgClient.SendMessage(msg.UserID, tgfmt.Sprintf(txtRecTbl, userCurrency))
 
 	case "/report_w", "/report_m", "/report_y":
-		return true, s.tgClient.SendMessage(msg.UserID, getReportByPeriod(s, msg))
+		period, err := reports.PeriodFromKey(strings.TrimPrefix(msg.Text, "/report_"), time.Now().In(getUserLocation(s, msg.UserID)))
+		if err != nil {
+			return true, err
+		}
+		return true, sendReportByPeriod(s, msg, period)
+	case "/report_status":
+		return true, s.tgClient.SendMessage(msg.UserID, getReportStatusAnswer(s, msg.UserID))
 	case "/add_cat":
 		s.lastUserCommand[msg.UserID] = "/add_cat"
 		return true, s.tgClient.SendMessage(msg.UserID, txtCatAdd)
@@ -452,14 +580,14 @@ func checkBotCommands(s *Model, msg Message) (bool, error) {
 		if btnCurr, err := getCurrencyButtons(s, userCurrency); err != nil {
 			return true, err
 		} else {
-			return true, s.tgClient.ShowInlineButtons(fmt.Sprintf(txtCurrencyChoice, userCurrency), btnCurr, msg.UserID)
+			return true, s.tgClient.ShowInlineButtons(tgfmt.Sprintf(txtCurrencyChoice, userCurrency), btnCurr, msg.UserID)
 		}
 	case "/set_limit":
 		s.lastUserCommand[msg.UserID] = "/set_limit"
-		answerText := fmt.Sprintf(txtLimitInfo, "без ограничений")
+		answerText := tgfmt.Sprintf(txtLimitInfo, "без ограничений")
 		userLimit, _ := getUserLimit(s, msg.UserID)
-		if userLimit > 0 {
-			answerText = fmt.Sprintf(txtLimitInfo, userLimit)
+		if userLimit.IsPositive() {
+			answerText = tgfmt.Sprintf(txtLimitInfo, formatSum(s, userLimit, getUserCurrency(s, msg.UserID)))
 		}
 		return true, s.tgClient.SendMessage(msg.UserID, answerText)
 	}
@@ -469,33 +597,176 @@ func checkBotCommands(s *Model, msg Message) (bool, error) {
 
 // Область "Распознавание входящих команд": конец.
 
+// Область "Курсы валют": начало.
+
+// Курсы валют из списка к валюте пользователя с изменением со вчерашнего дня.
+func getRatesAnswer(s *Model, userID int64) string {
+	ctx, span := tracer.Start(s.ctx, "getRatesAnswer")
+	s.ctx = ctx
+	defer span.End()
+
+	userCurrency := getUserCurrency(s, userID)
+	ratesDate := s.currencies.GetRatesDate()
+
+	if ratesDate.IsZero() {
+		return txtRatesError
+	}
+
+	prevRates, err := s.currencies.GetExchangeRatesOnDate(s.ctx, ratesDate.AddDate(0, 0, -1))
+	if err != nil {
+		logger.Error("Error getting previous exchange rates", "err", err)
+	}
+
+	var res strings.Builder
+	for _, currency := range s.currencies.GetCurrenciesList() {
+		if currency == userCurrency {
+			continue
+		}
+		rate, err := getCrossRate(s, currency, userCurrency)
+		if err != nil {
+			logger.Error("Error getting exchange rate", "currency", currency, "err", err)
+			continue
+		}
+		res.WriteString(tgfmt.Sprintf("%v %v %v", tgfmt.Code(currency), rate.FloatString(4), userCurrency))
+
+		if prevRate, err := crossRateFromRates(prevRates, currency, userCurrency); err == nil {
+			res.WriteString(" " + formatRateChange(rate, prevRate))
+		}
+		res.WriteString("\n")
+	}
+
+	if res.Len() == 0 {
+		return txtRatesError
+	}
+	return tgfmt.Sprintf(txtRates, userCurrency, ratesDate.Format("02.01.2006"), tgfmt.HTML(res.String())) + getRatesNote(s, "")
+}
+
+// Конвертация суммы по команде "/convert 100 USD EUR".
+func getConvertAnswer(s *Model, msg Message) string {
+	currenciesList := strings.Join(s.currencies.GetCurrenciesList(), ", ")
+
+	args := strings.Fields(strings.TrimPrefix(msg.Text, "/convert"))
+	if len(args) != 3 {
+		return tgfmt.Sprintf(txtConvertHelp, currenciesList)
+	}
+	from := strings.ToUpper(args[1])
+	to := strings.ToUpper(args[2])
+
+	sum, err := money.Parse(args[0], from)
+	if err != nil {
+		return tgfmt.Sprintf(txtConvertHelp, currenciesList)
+	}
+
+	rate, err := getCrossRate(s, from, to)
+	if err != nil {
+		logger.Error("Error getting exchange rate", "from", from, "to", to, "err", err)
+		return tgfmt.Sprintf(txtConvertHelp, currenciesList)
+	}
+
+	res, err := sum.Convert(rate, to)
+	if err != nil {
+		logger.Error("Error currency convertation", "err", err)
+		return txtRatesError
+	}
+	return tgfmt.Sprintf(txtConvertResult, sum, from, res, to, from, rate.FloatString(4), to) + getRatesNote(s, "")
+}
+
+// Кросс-курс: количество единиц валюты to за 1 единицу валюты from.
+func getCrossRate(s *Model, from string, to string) (money.Rate, error) {
+	rateFrom, err := s.currencies.GetExchangeRate(from)
+	if err != nil {
+		return money.Rate{}, err
+	}
+	rateTo, err := s.currencies.GetExchangeRate(to)
+	if err != nil {
+		return money.Rate{}, err
+	}
+	return rateTo.Div(rateFrom)
+}
+
+func crossRateFromRates(rates bottypes.ExchangeRate, from string, to string) (money.Rate, error) {
+	rateFrom, okFrom := rates[from]
+	rateTo, okTo := rates[to]
+	if !okFrom || !okTo {
+		return money.Rate{}, fmt.Errorf("no exchange rate for %s/%s", from, to)
+	}
+	return rateTo.Div(rateFrom)
+}
+
+// Форматирование изменения курса: "▲ +0.0123 (+0.38%)" (без процента, если вчерашний курс нулевой).
+func formatRateChange(rate money.Rate, prevRate money.Rate) string {
+	diff := new(big.Rat).Sub(rate.Rat(), prevRate.Rat())
+
+	sign := "+"
+	arrow := "▲"
+	switch diff.Sign() {
+	case -1:
+		sign = ""
+		arrow = "▼"
+	case 0:
+		arrow = "="
+	}
+	if prevRate.IsZero() {
+		return fmt.Sprintf("%v %v%v", arrow, sign, diff.FloatString(4))
+	}
+	percent := new(big.Rat).Mul(new(big.Rat).Quo(diff, prevRate.Rat()), big.NewRat(100, 1))
+	return fmt.Sprintf("%v %v%v (%v%v%%)", arrow, sign, diff.FloatString(4), sign, percent.FloatString(2))
+}
+
+// Область "Курсы валют": конец.
+
 // Область "Формирование отчета": начало.
 
-func getReportByPeriod(s *Model, msg Message) string {
+// Отправка текстового отчета за период: из кэша, если данные отчета уже сформированы, иначе запрос в очередь.
+func sendReportByPeriod(s *Model, msg Message, period reports.Period) error {
+	if s.reportCache != nil {
+		if cacheValue := s.reportCache.Get(msg.UserID, period.Key); cacheValue != nil {
+			if recs, ok := cacheValue.([]bottypes.UserDataReportRecord); ok {
+				// Отправка отчета конвертирует суммы записей, поэтому в кэше остаются суммы в базовой валюте.
+				return s.SendReportToUser(slices.Clone(recs), msg.UserID, period, "")
+			}
+			logger.Error("Error converting the cache value to report records")
+		}
+	}
+	return s.tgClient.SendMessage(msg.UserID, getReportByPeriod(s, msg, period, bottypes.ReportKindText))
+}
+
+func getReportByPeriod(s *Model, msg Message, period reports.Period, kind string) string {
 	ctx, span := tracer.Start(s.ctx, "getReportByPeriod")
 	s.ctx = ctx
 	defer span.End()
 
 	answerText := ""
-	reportKey := strings.Replace(msg.Text, "report_", "", -1)
-
-	// Ключ для поиска в кэше.
-	reportCacheKey := strconv.Itoa(int(msg.UserID)) + reportKey
-	// Попытка получить значение из кэша.
-	cacheValue := s.reportCache.Get(reportCacheKey)
-	if cacheValue != nil {
-		answerText, ok := cacheValue.(string)
-		if ok {
-			return answerText
-		} else {
-			logger.Error("Error converting the cache value to a string.")
-		}
-	}
 
 	//Отправка запроса на формирование отчета в кафку.
-	p, o, err := s.kafkaProducer.SendMessage(strconv.Itoa(int(msg.UserID)), reportKey)
+	req, err := reports.NewRequest(s.ctx, msg.UserID, period, kind, getUserCurrency(s, msg.UserID), time.Now())
+	if err != nil {
+		logger.Error("Error creating report request", "err", err)
+		return txtReportError
+	}
+	reqValue, err := reports.EncodeRequest(req)
+	if err != nil {
+		logger.Error("Error encoding report request", "err", err)
+		return txtReportError
+	}
+
+	// Повторный запрос того же отчета не ставится в очередь, пока предыдущий не выполнен.
+	reqStatus := bottypes.ReportRequestStatus{ID: req.RequestID, UserID: req.UserID, Period: req.Period, Kind: kind, RequestedAt: req.RequestedAt}
+	created, err := s.storage.InsertReportRequest(s.ctx, reqStatus, msg.UserName)
+	if err != nil {
+		logger.Error("Error saving report request", "err", err)
+		return txtReportError
+	}
+	if !created {
+		return txtReportInProgress
+	}
+
+	p, o, err := s.kafkaProducer.SendMessage(req.Key(), reqValue)
 	if err != nil {
 		logger.Error("Error send message to Kafka", "err", err)
+		if errStatus := s.storage.FinishReportRequest(s.ctx, req.RequestID, bottypes.ReportStatusFailed, err.Error()); errStatus != nil {
+			logger.Error("Error saving report request status", "err", errStatus)
+		}
 		answerText = txtReportError
 	} else {
 		logger.Debug(fmt.Sprintf("[KAFKA] Successful to write message, topic %s, offset: %d, partition: %d\n", s.kafkaProducer.GetTopic(), o, p))
@@ -505,35 +776,81 @@ func getReportByPeriod(s *Model, msg Message) string {
 	return answerText
 }
 
+// Состояние последних запросов пользователя на формирование отчетов.
+func getReportStatusAnswer(s *Model, userID int64) string {
+	requests, err := s.storage.GetUserReportRequests(s.ctx, userID, maxReportStatusRequests)
+	if err != nil {
+		logger.Error("Error getting report requests", "err", err)
+		return txtReportError
+	}
+	if len(requests) == 0 {
+		return txtReportStatusNone
+	}
+
+	var res strings.Builder
+	for _, req := range requests {
+		res.WriteString(tgfmt.Sprintf("%v - отчет за %v: %v\n", req.RequestedAt.Format("2006-01-02 15:04"), reports.PeriodName(req.Period), reportStatusName(req.Status)))
+	}
+	return tgfmt.Sprintf(txtReportStatus, tgfmt.HTML(res.String()))
+}
+
+func reportStatusName(status string) string {
+	switch status {
+	case bottypes.ReportStatusQueued:
+		return "в очереди"
+	case bottypes.ReportStatusRunning:
+		return "формируется"
+	case bottypes.ReportStatusDone:
+		return "отправлен"
+	case bottypes.ReportStatusFailed:
+		return "ошибка"
+	case bottypes.ReportStatusTimeout:
+		return "превышено время ожидания"
+	}
+	return status
+}
+
 func formatReport(s *Model, recs []bottypes.UserDataReportRecord, userCurrency string) string {
 	var res strings.Builder
-	totalSum := 0.0
-	for i, rec := range recs {
-		sumCurrency, err := s.currencies.ConvertSumFromBaseToCurrency(userCurrency, rec.Sum)
-		if err != nil {
-			logger.Error("Error currency convertation", "err", err)
-			return "ошибка конвертации валюты"
-		}
-		recs[i].Sum = sumCurrency
-		totalSum += sumCurrency
+	totalSum, err := convertReportSums(s, recs, userCurrency)
+	if err != nil {
+		return "ошибка конвертации валюты"
 	}
-	maxSumStr := fmt.Sprintf("%.2f", totalSum)
+	maxSumStr := totalSum.String()
 
-	res.WriteString(fmt.Sprintf("`%*s | %v`", len(maxSumStr)+1, "Сумма", "Категория") + "\n")
-	res.WriteString(fmt.Sprintf("`%v`", strings.Repeat("-", len(maxSumStr)+15)) + "\n")
+	res.WriteString(string(tgfmt.Code(fmt.Sprintf("%*s | %v", len(maxSumStr)+1, "Сумма", "Категория"))) + "\n")
+	res.WriteString(string(tgfmt.Code(strings.Repeat("-", len(maxSumStr)+15))) + "\n")
 
 	for _, rec := range recs {
-		// Форматирование категории и числа до нужной ширины.
-		res.WriteString(fmt.Sprintf("`%*.2f | %v`", len(maxSumStr)+1, rec.Sum, rec.Category) + "\n")
+		// Форматирование категории и числа до нужной ширины (название категории экранируется).
+		res.WriteString(string(tgfmt.Code(fmt.Sprintf("%*s | %v", len(maxSumStr)+1, rec.Sum, rec.Category))) + "\n")
 	}
 
 	if len(recs) > 0 {
-		res.WriteString(fmt.Sprintf("`%v`", strings.Repeat("-", len(maxSumStr)+15)) + "\n")
-		res.WriteString(fmt.Sprintf("`%*.2f | %v`", len(maxSumStr)+1, totalSum, "ИТОГО") + "\n")
+		res.WriteString(string(tgfmt.Code(strings.Repeat("-", len(maxSumStr)+15))) + "\n")
+		res.WriteString(string(tgfmt.Code(fmt.Sprintf("%*s | %v", len(maxSumStr)+1, totalSum, "ИТОГО"))) + "\n")
 	}
 	return res.String()
 }
 
+// Пересчет сумм отчета из базовой валюты в валюту пользователя. Возвращает итоговую сумму.
+func convertReportSums(s *Model, recs []bottypes.UserDataReportRecord, userCurrency string) (money.Money, error) {
+	totalSum := money.Zero(userCurrency)
+	for i, rec := range recs {
+		sumCurrency, err := s.currencies.ConvertSumFromBaseToCurrency(userCurrency, rec.Sum)
+		if err != nil {
+			logger.Error("Error currency convertation", "err", err)
+			return money.Money{}, err
+		}
+		recs[i].Sum = sumCurrency
+		if totalSum, err = totalSum.Add(sumCurrency); err != nil {
+			logger.Error("Error calculating total sum", "err", err)
+			return money.Money{}, err
+		}
+	}
+	return totalSum, nil
+}
+
 // Область "Получение данных пользователя": начало.
 func getCategoryButtons(s *Model, userID int64) ([]bottypes.TgRowButtons, error) {
 	userCategories, err := s.storage.GetUserCategories(s.ctx, userID)
@@ -589,20 +906,84 @@ func getUserCurrency(s *Model, userID int64) string {
 	return userCurrency
 }
 
-func getUserLimit(s *Model, userID int64) (float64, error) {
+// Валюта отчета: валюта из запроса на формирование отчета, а для запросов без валюты - текущая валюта пользователя.
+func getReportCurrency(s *Model, userID int64, currency string) string {
+	if currency != "" {
+		return currency
+	}
+	return getUserCurrency(s, userID)
+}
+
+// Часовой пояс пользователя (UTC, если часовой пояс не удалось получить).
+func getUserLocation(s *Model, userID int64) *time.Location {
+	timezone, err := s.storage.GetUserTimezone(s.ctx, userID)
+	if err != nil {
+		logger.Error("Error getting timezone", "err", err)
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		logger.Error("Error loading timezone", "timezone", timezone, "err", err)
+		return time.UTC
+	}
+	return loc
+}
+
+// Просмотр и изменение часового пояса по команде "/timezone Europe/Minsk".
+func getTimezoneAnswer(s *Model, msg Message) string {
+	timezone := strings.TrimSpace(strings.TrimPrefix(msg.Text, "/timezone"))
+	if timezone == "" {
+		loc := getUserLocation(s, msg.UserID)
+		return tgfmt.Sprintf(txtTimezoneInfo, loc, time.Now().In(loc).Format("02.01.2006 15:04"))
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "Local" {
+		return txtTimezoneError
+	}
+	if err := s.storage.SetUserTimezone(s.ctx, msg.UserID, loc.String(), msg.UserName); err != nil {
+		logger.Error("Error saving timezone", "err", err)
+		return txtReportError
+	}
+	invalidateUserCache(s, msg.UserID)
+	rescheduleDigests(s, msg.UserID, loc)
+	return tgfmt.Sprintf(txtTimezoneSet, loc)
+}
+
+func getUserLimit(s *Model, userID int64) (money.Money, error) {
 	userLimit, err := s.storage.GetUserLimit(s.ctx, userID)
 	if err != nil {
 		logger.Error("Error getting limit", "err", err)
-		return 0, err
+		return money.Money{}, err
 	}
 	return userLimit, nil
 }
 
+// Удаление отчетов пользователя из кэша после изменения его записей, категорий, валюты, бюджета или часового пояса.
+func invalidateUserCache(s *Model, userID int64) {
+	if s.reportCache != nil {
+		s.reportCache.RemoveUser(userID)
+	}
+}
+
+// Примечание о дате курсов, если для валюты пользователя используются устаревшие курсы.
+func getRatesNote(s *Model, userCurrency string) string {
+	if userCurrency == s.currencies.GetMainCurrency() || !s.currencies.IsRatesStale() {
+		return ""
+	}
+
+	updatedAt := s.currencies.GetRatesUpdateTime()
+	if updatedAt.IsZero() {
+		return ""
+	}
+	return "\n" + tgfmt.Sprintf(txtRatesStale, updatedAt.Format("02.01.2006 15:04"))
+}
+
 // Область "Получение данных пользователя": конец.
 
 // Область "Другие функции": начало.
 
-func parseLineRec(line string) (bottypes.UserDataRecord, error) {
+func parseLineRec(line string, currency string, loc *time.Location) (bottypes.UserDataRecord, error) {
 	matches := lineRegexp.FindStringSubmatch(line)
 	// [всё регулярное выражение], [Дата], [Цена], [Категория]
 	if len(matches) < 4 {
@@ -613,12 +994,12 @@ func parseLineRec(line string) (bottypes.UserDataRecord, error) {
 	priceStr := matches[2]
 	category := matches[3]
 
-	price, err := strconv.ParseFloat(priceStr, 64)
+	price, err := money.Parse(priceStr, currency)
 	if err != nil {
 		return bottypes.UserDataRecord{}, fmt.Errorf("incorrect price: %w", err)
 	}
 
-	date, err := time.Parse("2006-01-02", dateStr)
+	date, err := time.ParseInLocation("2006-01-02", dateStr, loc)
 	if err != nil {
 		return bottypes.UserDataRecord{}, fmt.Errorf("incorrect date: %w", err)
 	}
@@ -631,26 +1012,36 @@ func parseLineRec(line string) (bottypes.UserDataRecord, error) {
 
 }
 
-func convertSumFromCurrency(s *Model, userID int64, sum float64) (float64, error) {
-	userCurrency := getUserCurrency(s, userID)
-	sumBase, err := s.currencies.ConvertSumFromCurrencyToBase(userCurrency, sum)
+// Конвертация суммы из ее валюты в базовую.
+func convertSumFromCurrency(s *Model, sum money.Money) (money.Money, error) {
+	sumBase, err := s.currencies.ConvertSumFromCurrencyToBase(sum)
 	if err != nil {
 		logger.Error("Error convertation currency", "err", err)
-		return 0, err
+		return money.Money{}, err
 	}
 	return sumBase, nil
 
 }
 
-// Парсинг вводимого пользователем числа и конвертация суммы в базовую валюту.
-func parseAndConvertSumFromCurrency(s *Model, userID int64, sumString string) (float64, error) {
-	sum, err := strconv.ParseFloat(sumString, 64)
+// Парсинг вводимого пользователем числа (в валюте пользователя) и конвертация суммы в базовую валюту.
+func parseAndConvertSumFromCurrency(s *Model, userID int64, sumString string) (money.Money, error) {
+	sum, err := money.Parse(sumString, getUserCurrency(s, userID))
 	if err != nil {
-		return 0, fmt.Errorf("error parse sum: %w", err)
+		return money.Money{}, fmt.Errorf("error parse sum: %w", err)
 	}
 
-	if sum, err = convertSumFromCurrency(s, userID, sum); err != nil {
-		return 0, fmt.Errorf("error currency convertation: %w", err)
+	if sum, err = convertSumFromCurrency(s, sum); err != nil {
+		return money.Money{}, fmt.Errorf("error currency convertation: %w", err)
 	}
 	return sum, nil
 }
+
+// Форматирование суммы в базовой валюте для вывода в валюте пользователя.
+func formatSum(s *Model, sum money.Money, userCurrency string) string {
+	sumCurrency, err := s.currencies.ConvertSumFromBaseToCurrency(userCurrency, sum)
+	if err != nil {
+		logger.Error("Error currency convertation", "err", err)
+		return sum.String() + " " + sum.Currency()
+	}
+	return sumCurrency.String() + " " + userCurrency
+}
@@ -0,0 +1,177 @@
+package messages
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/helpers/tgfmt"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+const (
+	txtRateAlerts            = "Подписки на курсы валют. Для удаления подписки нажмите на нее."
+	txtRateAlertsEmpty       = "Подписок на курсы валют пока нет."
+	txtRateAlertAdd          = "Введите условие подписки, например:\n<code>USD &gt; 3.3</code> - курс выше 3.3 %v\n<code>EUR &lt; 3.5</code> - курс ниже 3.5 %v\n<code>EUR 2%%</code> - изменение курса за день больше чем на 2%%\nДля отмены введите 0."
+	txtRateAlertSave         = "Подписка сохранена. Уведомление придет после очередного обновления курсов."
+	txtRateAlertDelete       = "Подписка удалена."
+	txtRateAlertFormatError  = "Не удалось распознать условие подписки. Доступные валюты: %v"
+	txtRateAlertLimitReached = "Достигнуто максимальное количество подписок (%v). Удалите ненужные подписки."
+)
+
+// maxUserRateAlerts Максимальное количество подписок на курсы у пользователя.
+const maxUserRateAlerts = 10
+
+var (
+	// Условие на значение курса: "USD > 3.3", "USD/EUR < 0.9".
+	rateAlertThresholdRegexp = regexp.MustCompile(`^([A-Za-z]{3})(?:/([A-Za-z]{3}))?\s*([<>])\s*(\d+(?:[.,]\d+)?)$`)
+	// Условие на изменение курса за день: "EUR 2%", "USD/EUR 1.5%".
+	rateAlertChangeRegexp = regexp.MustCompile(`^([A-Za-z]{3})(?:/([A-Za-z]{3}))?\s+(\d+(?:[.,]\d+)?)\s*%$`)
+)
+
+// Проверка команд управления подписками на курсы валют и ввода условия подписки.
+func checkIfRateAlertCommand(s *Model, msg Message, lastUserCommand string) (bool, error) {
+	switch {
+	case lastUserCommand == "/rate_alert_add":
+		ctx, span := tracer.Start(s.ctx, "checkIfRateAlertCommand")
+		s.ctx = ctx
+		defer span.End()
+
+		if msg.Text == "0" {
+			// Отмена ввода подписки.
+			return true, nil
+		}
+		return true, s.tgClient.SendMessage(msg.UserID, addRateAlert(s, msg))
+
+	case msg.Text == "/rate_alerts":
+		return true, showRateAlerts(s, msg.UserID)
+
+	case msg.Text == "/rate_alert_add":
+		alerts, err := s.storage.GetUserRateAlerts(s.ctx, msg.UserID)
+		if err != nil {
+			logger.Error("Error getting rate alerts", "err", err)
+			return true, fmt.Errorf("get rate alerts error: %w", err)
+		}
+		if len(alerts) >= maxUserRateAlerts {
+			return true, s.tgClient.SendMessage(msg.UserID, tgfmt.Sprintf(txtRateAlertLimitReached, maxUserRateAlerts))
+		}
+
+		s.lastUserCommand[msg.UserID] = "/rate_alert_add"
+		userCurrency := getUserCurrency(s, msg.UserID)
+		return true, s.tgClient.SendMessage(msg.UserID, tgfmt.Sprintf(txtRateAlertAdd, userCurrency, userCurrency))
+
+	case msg.IsCallback && strings.HasPrefix(msg.Text, "/rate_alert_del "):
+		alertID, err := strconv.ParseInt(strings.TrimPrefix(msg.Text, "/rate_alert_del "), 10, 64)
+		if err != nil {
+			return true, fmt.Errorf("incorrect rate alert id: %w", err)
+		}
+		if err := s.storage.DeleteRateAlert(s.ctx, msg.UserID, alertID); err != nil {
+			logger.Error("Error deleting rate alert", "err", err)
+			return true, fmt.Errorf("delete rate alert error: %w", err)
+		}
+		return true, s.tgClient.SendMessage(msg.UserID, txtRateAlertDelete)
+	}
+
+	return false, nil
+}
+
+// Отображение подписок пользователя кнопками для удаления.
+func showRateAlerts(s *Model, userID int64) error {
+	alerts, err := s.storage.GetUserRateAlerts(s.ctx, userID)
+	if err != nil {
+		logger.Error("Error getting rate alerts", "err", err)
+		return fmt.Errorf("get rate alerts error: %w", err)
+	}
+
+	answerText := txtRateAlerts
+	if len(alerts) == 0 {
+		answerText = txtRateAlertsEmpty
+	}
+
+	buttons := make([]bottypes.TgRowButtons, 0, len(alerts)+1)
+	for _, alert := range alerts {
+		buttons = append(buttons, bottypes.TgRowButtons{
+			bottypes.TgInlineButton{DisplayName: formatRateAlert(alert), Value: fmt.Sprintf("/rate_alert_del %d", alert.ID)},
+		})
+	}
+	buttons = append(buttons, bottypes.TgRowButtons{bottypes.TgInlineButton{DisplayName: "Добавить подписку", Value: "/rate_alert_add"}})
+
+	return s.tgClient.ShowInlineButtons(answerText, buttons, userID)
+}
+
+// Разбор и сохранение условия подписки.
+func addRateAlert(s *Model, msg Message) string {
+	userCurrency := getUserCurrency(s, msg.UserID)
+	formatError := tgfmt.Sprintf(txtRateAlertFormatError, strings.Join(s.currencies.GetCurrenciesList(), ", "))
+
+	alert, err := parseRateAlert(strings.TrimSpace(msg.Text), userCurrency)
+	if err != nil {
+		return formatError
+	}
+	alert.UserID = msg.UserID
+
+	// Проверка наличия курсов для валют подписки.
+	if _, err := getCrossRate(s, alert.Currency, alert.BaseCurrency); err != nil || alert.Currency == alert.BaseCurrency {
+		return formatError
+	}
+
+	if err := s.storage.InsertRateAlert(s.ctx, alert, msg.UserName); err != nil {
+		logger.Error("Error saving rate alert", "err", err)
+		return txtReportError
+	}
+	return txtRateAlertSave
+}
+
+func parseRateAlert(text string, userCurrency string) (bottypes.RateAlert, error) {
+	alert := bottypes.RateAlert{BaseCurrency: userCurrency}
+	var thresholdStr string
+
+	if matches := rateAlertThresholdRegexp.FindStringSubmatch(text); matches != nil {
+		// [всё регулярное выражение], [Валюта], [Валюта курса], [Условие], [Порог]
+		alert.Currency = matches[1]
+		if matches[2] != "" {
+			alert.BaseCurrency = matches[2]
+		}
+		alert.Kind = bottypes.RateAlertAbove
+		if matches[3] == "<" {
+			alert.Kind = bottypes.RateAlertBelow
+		}
+		thresholdStr = matches[4]
+	} else if matches := rateAlertChangeRegexp.FindStringSubmatch(text); matches != nil {
+		// [всё регулярное выражение], [Валюта], [Валюта курса], [Процент]
+		alert.Currency = matches[1]
+		if matches[2] != "" {
+			alert.BaseCurrency = matches[2]
+		}
+		alert.Kind = bottypes.RateAlertChange
+		thresholdStr = matches[3]
+	} else {
+		return bottypes.RateAlert{}, fmt.Errorf("incorrect rate alert %q", text)
+	}
+
+	threshold, err := money.ParseRate(thresholdStr)
+	if err != nil || !threshold.IsPositive() {
+		return bottypes.RateAlert{}, fmt.Errorf("incorrect rate alert threshold %q", thresholdStr)
+	}
+	alert.Threshold = threshold
+	alert.Currency = strings.ToUpper(alert.Currency)
+	alert.BaseCurrency = strings.ToUpper(alert.BaseCurrency)
+
+	return alert, nil
+}
+
+// Описание подписки: "USD/BYN > 3.3", "EUR/BYN ± 2%".
+func formatRateAlert(alert bottypes.RateAlert) string {
+	pair := alert.Currency + "/" + alert.BaseCurrency
+	switch alert.Kind {
+	case bottypes.RateAlertAbove:
+		return fmt.Sprintf("%v > %v", pair, alert.Threshold)
+	case bottypes.RateAlertBelow:
+		return fmt.Sprintf("%v < %v", pair, alert.Threshold)
+	default:
+		return fmt.Sprintf("%v ± %v%%", pair, alert.Threshold)
+	}
+}
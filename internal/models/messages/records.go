@@ -0,0 +1,191 @@
+package messages
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/tgfmt"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+	"github.com/shoksin/financesBot/internal/models/reports"
+)
+
+const (
+	txtRecordsTitle = "Расходы по категории <code>%v</code> за <b>%v</b> (%v), %v. Страница %v из %v:\n%v"
+	txtRecordsEmpty = "Нет записей по категории за этот период."
+	txtTopTitle     = "Самые крупные расходы за <b>%v</b> (%v):\n%v"
+	txtTopEmpty     = "Нет расходов за этот период."
+)
+
+const (
+	// Записи категории из отчета: "/rec 20240101-20240331 12 sum 0" (ключ периода, ID категории, порядок, страница).
+	cmdRecords = "/rec "
+	// Самые крупные расходы за период: "/report_top", "/report_top прошлый месяц".
+	cmdReportTop = "/report_top"
+)
+
+const (
+	// recordsPageSize Количество записей на странице записей категории.
+	recordsPageSize = 10
+	// topRecordsCount Количество записей в отчете о самых крупных расходах.
+	topRecordsCount = 10
+	// maxReportCategoryButtons Количество категорий отчета с кнопками просмотра записей.
+	maxReportCategoryButtons = 12
+)
+
+// Кнопки просмотра записей категорий и самых крупных расходов под текстовым отчетом.
+func reportRecordsButtons(periodKey string, recs []bottypes.UserDataReportRecord) []bottypes.TgRowButtons {
+	buttons := []bottypes.TgRowButtons{}
+	for i, rec := range recs {
+		if i == maxReportCategoryButtons {
+			break
+		}
+		if i%2 == 0 {
+			buttons = append(buttons, bottypes.TgRowButtons{})
+		}
+		buttons[len(buttons)-1] = append(buttons[len(buttons)-1], bottypes.TgInlineButton{
+			DisplayName: rec.Category,
+			Value:       recordsCommand(periodKey, rec.CategoryID, bottypes.RecordsOrderSum, 0),
+		})
+	}
+	return append(buttons, bottypes.TgRowButtons{
+		bottypes.TgInlineButton{DisplayName: fmt.Sprintf("Топ-%v расходов", topRecordsCount), Value: cmdReportTop + " " + periodKey},
+	})
+}
+
+func recordsCommand(periodKey string, categoryID int64, order string, page int) string {
+	return fmt.Sprintf("%v%v %v %v %v", cmdRecords, periodKey, categoryID, order, page)
+}
+
+// Проверка нажатия кнопки просмотра записей категории из отчета.
+func checkIfRecordsCommand(s *Model, msg Message) (bool, error) {
+	if !msg.IsCallback || !strings.HasPrefix(msg.Text, cmdRecords) {
+		return false, nil
+	}
+
+	ctx, span := tracer.Start(s.ctx, "checkIfRecordsCommand")
+	s.ctx = ctx
+	defer span.End()
+
+	// [Ключ периода], [ID категории], [Порядок], [Страница]
+	args := strings.Fields(strings.TrimPrefix(msg.Text, cmdRecords))
+	if len(args) != 4 || (args[2] != bottypes.RecordsOrderSum && args[2] != bottypes.RecordsOrderDate) {
+		return true, fmt.Errorf("incorrect records command %q", msg.Text)
+	}
+	categoryID, errCategory := strconv.ParseInt(args[1], 10, 64)
+	page, errPage := strconv.Atoi(args[3])
+	if errCategory != nil || errPage != nil || categoryID <= 0 || page < 0 {
+		return true, fmt.Errorf("incorrect records command %q", msg.Text)
+	}
+	period, err := reports.PeriodFromKey(args[0], time.Now().In(getUserLocation(s, msg.UserID)))
+	if err != nil {
+		return true, err
+	}
+
+	q := bottypes.RecordsQuery{From: period.From, To: period.To, CategoryID: categoryID, Order: args[2], Limit: recordsPageSize, Offset: page * recordsPageSize}
+	recs, total, err := s.storage.GetUserRecordsPage(s.ctx, msg.UserID, q)
+	if err != nil {
+		logger.Error("Error getting user records", "err", err)
+		return true, fmt.Errorf("get user records error: %w", err)
+	}
+	if len(recs) == 0 {
+		return true, s.tgClient.SendMessage(msg.UserID, txtRecordsEmpty)
+	}
+
+	userCurrency := getUserCurrency(s, msg.UserID)
+	lines, err := formatRecordLines(s, recs, userCurrency, false)
+	if err != nil {
+		return true, s.tgClient.SendMessage(msg.UserID, txtReportError)
+	}
+	pages := (total + recordsPageSize - 1) / recordsPageSize
+	orderName := "по убыванию суммы"
+	if q.Order == bottypes.RecordsOrderDate {
+		orderName = "от новых к старым"
+	}
+	text := tgfmt.Sprintf(txtRecordsTitle, recs[0].Category, period.Name(), userCurrency, orderName, page+1, pages, tgfmt.HTML(lines)) + getRatesNote(s, userCurrency)
+
+	// Переход по страницам и смена порядка записей.
+	nav := bottypes.TgRowButtons{}
+	if page > 0 {
+		nav = append(nav, bottypes.TgInlineButton{DisplayName: "◀ Назад", Value: recordsCommand(period.Key, categoryID, q.Order, page-1)})
+	}
+	if page+1 < pages {
+		nav = append(nav, bottypes.TgInlineButton{DisplayName: "Вперёд ▶", Value: recordsCommand(period.Key, categoryID, q.Order, page+1)})
+	}
+	order := bottypes.TgInlineButton{DisplayName: "По дате", Value: recordsCommand(period.Key, categoryID, bottypes.RecordsOrderDate, 0)}
+	if q.Order == bottypes.RecordsOrderDate {
+		order = bottypes.TgInlineButton{DisplayName: "По сумме", Value: recordsCommand(period.Key, categoryID, bottypes.RecordsOrderSum, 0)}
+	}
+	buttons := []bottypes.TgRowButtons{{order}}
+	if len(nav) > 0 {
+		buttons = append([]bottypes.TgRowButtons{nav}, buttons...)
+	}
+	return true, s.tgClient.ShowInlineButtons(text, buttons, msg.UserID)
+}
+
+// Отчет о самых крупных расходах за период ("/report_top" - за этот месяц).
+func topRecords(s *Model, msg Message) error {
+	ctx, span := tracer.Start(s.ctx, "topRecords")
+	s.ctx = ctx
+	defer span.End()
+
+	now := time.Now().In(getUserLocation(s, msg.UserID))
+	text := strings.TrimSpace(strings.TrimPrefix(msg.Text, cmdReportTop))
+	if text == "" {
+		text = "этот месяц"
+	}
+	period, err := reports.PeriodFromKey(text, now)
+	if err != nil {
+		if period, err = reports.ParsePeriod(text, now); err != nil {
+			return s.tgClient.SendMessage(msg.UserID, txtReportPeriodError+"\n"+txtReportQP)
+		}
+	}
+
+	q := bottypes.RecordsQuery{From: period.From, To: period.To, Order: bottypes.RecordsOrderSum, Limit: topRecordsCount}
+	recs, _, err := s.storage.GetUserRecordsPage(s.ctx, msg.UserID, q)
+	if err != nil {
+		logger.Error("Error getting user records", "err", err)
+		return fmt.Errorf("get user records error: %w", err)
+	}
+	if len(recs) == 0 {
+		return s.tgClient.SendMessage(msg.UserID, txtTopEmpty)
+	}
+
+	userCurrency := getUserCurrency(s, msg.UserID)
+	lines, err := formatRecordLines(s, recs, userCurrency, true)
+	if err != nil {
+		return s.tgClient.SendMessage(msg.UserID, txtReportError)
+	}
+	return s.tgClient.SendMessage(msg.UserID, tgfmt.Sprintf(txtTopTitle, period.Name(), userCurrency, tgfmt.HTML(lines))+getRatesNote(s, userCurrency))
+}
+
+// Строки записей о расходах с разметкой: дата, сумма в валюте пользователя, категория (если withCategory) и комментарий.
+func formatRecordLines(s *Model, recs []bottypes.UserDataRecord, userCurrency string, withCategory bool) (string, error) {
+	userLocation := getUserLocation(s, recs[0].UserID)
+	sums := make([]string, len(recs))
+	width := 0
+	for i, rec := range recs {
+		sum, err := s.currencies.ConvertSumFromBaseToCurrency(userCurrency, rec.Sum)
+		if err != nil {
+			logger.Error("Error currency convertation", "err", err)
+			return "", err
+		}
+		sums[i] = sum.String()
+		width = max(width, len(sums[i]))
+	}
+
+	var res strings.Builder
+	for i, rec := range recs {
+		line := fmt.Sprintf("%v %*s", rec.Period.In(userLocation).Format("02.01.2006"), width, sums[i])
+		if withCategory {
+			line += " " + rec.Category
+		}
+		if rec.Comment != "" {
+			line += " " + rec.Comment
+		}
+		res.WriteString(string(tgfmt.Code(line)) + "\n")
+	}
+	return res.String(), nil
+}
@@ -0,0 +1,159 @@
+package ratealerts
+
+// Проверка подписок пользователей на изменение курсов валют и отправка уведомлений.
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/money"
+	"github.com/shoksin/financesBot/internal/helpers/tgfmt"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+const (
+	txtAlertAbove  = "Курс <b>%v/%v</b> = %v и превысил %v."
+	txtAlertBelow  = "Курс <b>%v/%v</b> = %v и опустился ниже %v."
+	txtAlertChange = "Курс <b>%v/%v</b> = %v изменился за день на %v%%."
+)
+
+// MessagesSender Интерфейс для отправки уведомлений.
+type MessagesSender interface {
+	SendMessage(userID int64, text string) error
+}
+
+// AlertsStorage Интерфейс хранилища подписок.
+type AlertsStorage interface {
+	GetRateAlerts(ctx context.Context) ([]bottypes.RateAlert, error)
+	UpdateRateAlertState(ctx context.Context, alert bottypes.RateAlert) error
+}
+
+// ExchangeRates Интерфейс для получения курсов валют.
+type ExchangeRates interface {
+	GetExchangeRate(currencyName string) (money.Rate, error)
+	GetRatesDate() time.Time
+	GetExchangeRatesOnDate(ctx context.Context, date time.Time) (bottypes.ExchangeRate, error)
+}
+
+type Checker struct {
+	storage    AlertsStorage
+	sender     MessagesSender
+	currencies ExchangeRates
+}
+
+func New(storage AlertsStorage, sender MessagesSender, currencies ExchangeRates) *Checker {
+	return &Checker{storage: storage, sender: sender, currencies: currencies}
+}
+
+// CheckAlerts Проверка всех подписок по текущим курсам (вызывается после обновления курсов).
+func (c *Checker) CheckAlerts(ctx context.Context) {
+	alerts, err := c.storage.GetRateAlerts(ctx)
+	if err != nil {
+		logger.Error("Error getting rate alerts", "err", err)
+		return
+	}
+	if len(alerts) == 0 {
+		return
+	}
+
+	ratesDate := c.currencies.GetRatesDate()
+	prevRates, err := c.currencies.GetExchangeRatesOnDate(ctx, ratesDate.AddDate(0, 0, -1))
+	if err != nil {
+		logger.Error("Error getting previous exchange rates", "err", err)
+	}
+
+	for _, alert := range alerts {
+		text, changed, err := c.checkAlert(&alert, ratesDate, prevRates)
+		if err != nil {
+			logger.Error("Error checking rate alert", "id", alert.ID, "err", err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		if text != "" {
+			if err := c.sender.SendMessage(alert.UserID, text); err != nil {
+				logger.Error("Error sending rate alert", "id", alert.ID, "err", err)
+				continue
+			}
+		}
+		if err := c.storage.UpdateRateAlertState(ctx, alert); err != nil {
+			logger.Error("Error saving rate alert state", "id", alert.ID, "err", err)
+		}
+	}
+}
+
+// checkAlert Проверка условия подписки. Возвращает текст уведомления и признак изменения состояния подписки.
+func (c *Checker) checkAlert(alert *bottypes.RateAlert, ratesDate time.Time, prevRates bottypes.ExchangeRate) (string, bool, error) {
+	rate, err := c.crossRate(alert.Currency, alert.BaseCurrency)
+	if err != nil {
+		return "", false, err
+	}
+
+	switch alert.Kind {
+	case bottypes.RateAlertAbove, bottypes.RateAlertBelow:
+		cmp := rate.Rat().Cmp(alert.Threshold.Rat())
+		isMet := cmp > 0
+		txtAlert := txtAlertAbove
+		if alert.Kind == bottypes.RateAlertBelow {
+			isMet = cmp < 0
+			txtAlert = txtAlertBelow
+		}
+
+		if isMet == alert.Triggered {
+			return "", false, nil
+		}
+		alert.Triggered = isMet
+		if !isMet {
+			// Курс вернулся за порог: уведомление будет отправлено при следующем пересечении.
+			return "", true, nil
+		}
+		return tgfmt.Sprintf(txtAlert, alert.Currency, alert.BaseCurrency, rate.FloatString(4), alert.Threshold), true, nil
+
+	case bottypes.RateAlertChange:
+		if prevRates == nil || alert.LastRatesDate.Equal(ratesDate) {
+			return "", false, nil
+		}
+		rateFrom, okFrom := prevRates[alert.Currency]
+		rateTo, okTo := prevRates[alert.BaseCurrency]
+		if !okFrom || !okTo {
+			return "", false, fmt.Errorf("no previous exchange rate for %s/%s", alert.Currency, alert.BaseCurrency)
+		}
+		prevRate, err := rateTo.Div(rateFrom)
+		if err != nil {
+			return "", false, err
+		}
+		if prevRate.IsZero() {
+			// Кросс-курс округлился до нуля: изменение в процентах не определено.
+			return "", false, nil
+		}
+
+		diff := new(big.Rat).Sub(rate.Rat(), prevRate.Rat())
+		percent := new(big.Rat).Mul(new(big.Rat).Quo(diff, prevRate.Rat()), big.NewRat(100, 1))
+		if new(big.Rat).Abs(percent).Cmp(alert.Threshold.Rat()) < 0 {
+			return "", false, nil
+		}
+
+		alert.LastRatesDate = ratesDate
+		return tgfmt.Sprintf(txtAlertChange, alert.Currency, alert.BaseCurrency, rate.FloatString(4), percent.FloatString(2)), true, nil
+	}
+
+	return "", false, fmt.Errorf("unknown rate alert kind %s", alert.Kind)
+}
+
+// crossRate Количество единиц валюты to за 1 единицу валюты from.
+func (c *Checker) crossRate(from string, to string) (money.Rate, error) {
+	rateFrom, err := c.currencies.GetExchangeRate(from)
+	if err != nil {
+		return money.Rate{}, err
+	}
+	rateTo, err := c.currencies.GetExchangeRate(to)
+	if err != nil {
+		return money.Rate{}, err
+	}
+	return rateTo.Div(rateFrom)
+}
@@ -0,0 +1,199 @@
+package reports
+
+// Формирование отчетов по запросам из очереди.
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/timeutils"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer trace.Tracer
+
+func init() {
+	tracer = otel.Tracer("models/reports")
+}
+
+// ReportStorage Интерфейс хранилища данных для отчетов.
+type ReportStorage interface {
+	GetUserDataRecord(ctx context.Context, userID int64, from time.Time, to time.Time) ([]bottypes.UserDataReportRecord, error)
+	StartReportRequest(ctx context.Context, requestID string) (bool, error)
+	FinishReportRequest(ctx context.Context, requestID string, status string, errText string) error
+	GetUserDataByDate(ctx context.Context, userID int64, from time.Time, to time.Time, unit string, timezone string) ([]bottypes.UserDataDateRecord, error)
+	GetUserTimezone(ctx context.Context, userID int64) (string, error)
+}
+
+// ReportSender Интерфейс отправки сформированного отчета пользователю.
+// Суммы выводятся в валюте currency из запроса (пустая - текущая валюта пользователя).
+type ReportSender interface {
+	SendReportToUser(dt []bottypes.UserDataReportRecord, userID int64, period Period, currency string) error
+	SendPieChart(dt []bottypes.UserDataReportRecord, userID int64, period Period, currency string) error
+	SendBarChart(dt []bottypes.UserDataDateRecord, userID int64, period Period, unit timeutils.Unit, currency string) error
+	SendCompareReport(dt []bottypes.UserDataReportRecord, prevDt []bottypes.UserDataReportRecord, userID int64, period Period, prevPeriod Period, currency string) error
+	SendReportError(userID int64, period Period) error
+}
+
+// ReportCache Интерфейс кэша данных текстовых отчетов (записи хранятся по пользователю и ключу периода).
+// Поколение кэша получается до чтения записей, чтобы не сохранить в кэш записи,
+// измененные пользователем во время формирования отчета.
+type ReportCache interface {
+	Generation() uint64
+	Add(userID int64, generation uint64, key string, value any)
+}
+
+// maxDailyChartDays Максимальная длина периода (в днях), для которой график строится по дням.
+const maxDailyChartDays = 62
+
+type Builder struct {
+	storage ReportStorage
+	sender  ReportSender
+	cache   ReportCache // Кэш данных текстовых отчетов (nil - отчеты не кэшируются).
+	retried bool        // Очередь повторно выполняет запросы, завершившиеся ошибкой.
+	mu      sync.Mutex  // Модель бота не рассчитана на параллельные вызовы, отправка отчетов выполняется по очереди.
+}
+
+// NewBuilder Формирование отчетов с сохранением данных текстовых отчетов в кэш cache (может быть nil).
+// Кэш используется только в процессе бота, который удаляет из него записи пользователя при изменении его данных.
+func NewBuilder(storage ReportStorage, sender ReportSender, cache ReportCache) *Builder {
+	return &Builder{storage: storage, sender: sender, cache: cache}
+}
+
+// WithRetries Обработка запросов из очереди, которая повторяет запросы после ошибки (очередь в Postgres).
+// Запрос, завершившийся ошибкой, остается в состоянии "в очереди" до повторной попытки.
+func (b *Builder) WithRetries() *Builder {
+	b.retried = true
+	return b
+}
+
+// BuildReport Формирование и отправка отчета по запросу из очереди (ключ - ID пользователя, значение - запрос, см. Request).
+func (b *Builder) BuildReport(ctx context.Context, key string, value string) error {
+	req, err := DecodeRequest(key, value, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, span := tracer.Start(req.Context(ctx), "BuildReport")
+	defer span.End()
+
+	// Запросы старого формата не имеют ID и не отслеживаются.
+	if req.RequestID != "" {
+		started, err := b.storage.StartReportRequest(ctx, req.RequestID)
+		if err != nil {
+			return fmt.Errorf("start report request: %w", err)
+		}
+		if !started {
+			logger.Info("Skip report request that is not queued", "requestID", req.RequestID, "userID", req.UserID)
+			return nil
+		}
+	}
+
+	err = b.buildReport(ctx, req)
+	if req.RequestID != "" {
+		// При ошибке запрос возвращается в очередь, только если очередь выполнит его повторно
+		// (после последней попытки он будет просрочен). Kafka и очередь в памяти запросы не повторяют.
+		status, errText := bottypes.ReportStatusDone, ""
+		if err != nil {
+			status, errText = bottypes.ReportStatusFailed, err.Error()
+			if b.retried {
+				status = bottypes.ReportStatusQueued
+			}
+		}
+		if errFinish := b.storage.FinishReportRequest(ctx, req.RequestID, status, errText); errFinish != nil {
+			logger.Error("Error saving report request status", "requestID", req.RequestID, "err", errFinish)
+		}
+		if status == bottypes.ReportStatusFailed {
+			b.mu.Lock()
+			if errSend := b.sender.SendReportError(req.UserID, req.ReportPeriod()); errSend != nil {
+				logger.Error("Error sending report error", "requestID", req.RequestID, "err", errSend)
+			}
+			b.mu.Unlock()
+		}
+	}
+	return err
+}
+
+func (b *Builder) buildReport(ctx context.Context, req Request) error {
+	switch req.Format {
+	case FormatBar:
+		return b.buildBarChart(ctx, req)
+	case FormatCompare:
+		return b.buildCompareReport(ctx, req)
+	}
+
+	var generation uint64
+	if b.cache != nil {
+		generation = b.cache.Generation()
+	}
+	recs, err := b.storage.GetUserDataRecord(ctx, req.UserID, req.From, req.To)
+	if err != nil {
+		return fmt.Errorf("get user data records: %w", err)
+	}
+	if b.cache != nil && req.Format == FormatText {
+		// Суммы в кэше остаются в базовой валюте, т.к. отправка отчета конвертирует их в переданном срезе.
+		b.cache.Add(req.UserID, generation, req.Period, slices.Clone(recs))
+	}
+
+	logger.Info("Report is built", "requestID", req.RequestID, "userID", req.UserID, "reportKey", req.Period, "records", len(recs))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if req.Format == FormatPie {
+		return b.sender.SendPieChart(recs, req.UserID, req.ReportPeriod(), req.Currency)
+	}
+	return b.sender.SendReportToUser(recs, req.UserID, req.ReportPeriod(), req.Currency)
+}
+
+// buildBarChart Формирование графика расходов по дням (по месяцам для длинных периодов).
+func (b *Builder) buildBarChart(ctx context.Context, req Request) error {
+	timezone, err := b.storage.GetUserTimezone(ctx, req.UserID)
+	if err != nil {
+		return fmt.Errorf("get user timezone: %w", err)
+	}
+
+	period := req.ReportPeriod()
+	unit := BarChartUnit(period)
+	recs, err := b.storage.GetUserDataByDate(ctx, req.UserID, req.From, req.To, unit.String(), timezone)
+	if err != nil {
+		return fmt.Errorf("get user data by date: %w", err)
+	}
+
+	logger.Info("Report chart is built", "requestID", req.RequestID, "userID", req.UserID, "reportKey", req.Period, "records", len(recs))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sender.SendBarChart(recs, req.UserID, period, unit, req.Currency)
+}
+
+// buildCompareReport Формирование сравнения расходов по категориям с предыдущим периодом той же длины.
+func (b *Builder) buildCompareReport(ctx context.Context, req Request) error {
+	period := req.ReportPeriod()
+	prevPeriod := period.Prev()
+
+	recs, err := b.storage.GetUserDataRecord(ctx, req.UserID, period.From, period.To)
+	if err != nil {
+		return fmt.Errorf("get user data records: %w", err)
+	}
+	prevRecs, err := b.storage.GetUserDataRecord(ctx, req.UserID, prevPeriod.From, prevPeriod.To)
+	if err != nil {
+		return fmt.Errorf("get previous user data records: %w", err)
+	}
+
+	logger.Info("Compare report is built", "requestID", req.RequestID, "userID", req.UserID, "reportKey", req.Period, "records", len(recs), "prevRecords", len(prevRecs))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sender.SendCompareReport(recs, prevRecs, req.UserID, period, prevPeriod, req.Currency)
+}
+
+// BarChartUnit Шаг графика расходов: день для периодов до двух месяцев, иначе месяц.
+func BarChartUnit(period Period) timeutils.Unit {
+	if period.To.Sub(period.From) <= maxDailyChartDays*24*time.Hour {
+		return timeutils.Day
+	}
+	return timeutils.Month
+}
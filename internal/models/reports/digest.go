@@ -0,0 +1,172 @@
+package reports
+
+// Регулярные отчеты (дайджесты) по подпискам пользователей.
+// Время следующей отправки хранится в базе данных: после перезапуска пропущенные отчеты отправляются
+// один раз за последний завершенный период, а при нескольких экземплярах бота каждый отчет забирает только один из них.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/timeutils"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+// DigestStorage Интерфейс хранилища подписок на регулярные отчеты.
+type DigestStorage interface {
+	GetDueDigests(ctx context.Context, now time.Time) ([]bottypes.Digest, error)
+	ClaimDigest(ctx context.Context, digestID int64, runAt time.Time, nextRunAt time.Time, now time.Time) (bool, error)
+	GetUserTimezone(ctx context.Context, userID int64) (string, error)
+	GetUserCurrency(ctx context.Context, userID int64) (string, error)
+	InsertReportRequest(ctx context.Context, req bottypes.ReportRequestStatus, userName string) (bool, error)
+	FinishReportRequest(ctx context.Context, requestID string, status string, errText string) error
+}
+
+// Producer Интерфейс очереди запросов на формирование отчетов.
+type Producer interface {
+	SendMessage(key string, value string) (partition int32, offset int64, err error)
+}
+
+// DigestScheduler Постановка в очередь регулярных отчетов, время отправки которых наступило.
+type DigestScheduler struct {
+	storage  DigestStorage
+	producer Producer
+}
+
+func NewDigestScheduler(storage DigestStorage, producer Producer) *DigestScheduler {
+	return &DigestScheduler{storage: storage, producer: producer}
+}
+
+// RunDue Постановка в очередь отчетов по подпискам со временем отправки не позже now.
+func (d *DigestScheduler) RunDue(ctx context.Context, now time.Time) {
+	ctx, span := tracer.Start(ctx, "RunDigests")
+	defer span.End()
+
+	digests, err := d.storage.GetDueDigests(ctx, now)
+	if err != nil {
+		logger.Error("Error getting due digests", "err", err)
+		return
+	}
+
+	for _, digest := range digests {
+		if err := d.run(ctx, digest, now); err != nil {
+			logger.Error("Error sending digest", "digestID", digest.ID, "userID", digest.UserID, "err", err)
+		}
+	}
+}
+
+// AutoRun Периодическая проверка подписок до отмены контекста.
+func (d *DigestScheduler) AutoRun(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	d.RunDue(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.RunDue(ctx, time.Now())
+		}
+	}
+}
+
+func (d *DigestScheduler) run(ctx context.Context, digest bottypes.Digest, now time.Time) error {
+	timezone, err := d.storage.GetUserTimezone(ctx, digest.UserID)
+	if err != nil {
+		return fmt.Errorf("get user timezone: %w", err)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return fmt.Errorf("load user timezone %q: %w", timezone, err)
+	}
+
+	// Сначала переносится время отправки: отчет не будет отправлен дважды даже при ошибке постановки в очередь.
+	claimed, err := d.storage.ClaimDigest(ctx, digest.ID, digest.NextRunAt, NextDigestRun(digest, now, loc), now)
+	if err != nil {
+		return fmt.Errorf("claim digest: %w", err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	period, err := DigestPeriod(digest, now, loc)
+	if err != nil {
+		return err
+	}
+	currency, err := d.storage.GetUserCurrency(ctx, digest.UserID)
+	if err != nil {
+		return fmt.Errorf("get user currency: %w", err)
+	}
+
+	req, err := NewRequest(ctx, digest.UserID, period, FormatText, currency, now)
+	if err != nil {
+		return err
+	}
+	value, err := EncodeRequest(req)
+	if err != nil {
+		return err
+	}
+
+	reqStatus := bottypes.ReportRequestStatus{ID: req.RequestID, UserID: req.UserID, Period: req.Period, Kind: FormatText, RequestedAt: req.RequestedAt}
+	created, err := d.storage.InsertReportRequest(ctx, reqStatus, "")
+	if err != nil {
+		return fmt.Errorf("save report request: %w", err)
+	}
+	if !created {
+		// Такой же отчет уже в очереди.
+		return nil
+	}
+
+	if _, _, err := d.producer.SendMessage(req.Key(), value); err != nil {
+		if errStatus := d.storage.FinishReportRequest(ctx, req.RequestID, bottypes.ReportStatusFailed, err.Error()); errStatus != nil {
+			logger.Error("Error saving report request status", "requestID", req.RequestID, "err", errStatus)
+		}
+		return fmt.Errorf("send report request: %w", err)
+	}
+	logger.Info("Digest is queued", "digestID", digest.ID, "userID", digest.UserID, "reportKey", req.Period)
+	return nil
+}
+
+// NextDigestRun Ближайшее после after время отправки отчета по подписке в часовом поясе loc.
+func NextDigestRun(digest bottypes.Digest, after time.Time, loc *time.Location) time.Time {
+	t := after.In(loc)
+	hour, minute := digest.SendTime/60, digest.SendTime%60
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, loc)
+	}
+
+	var next time.Time
+	switch digest.Frequency {
+	case bottypes.DigestWeekly:
+		days := (int(digest.Weekday) - int(t.Weekday()) + 7) % 7
+		if next = at(t.Year(), t.Month(), t.Day()+days); !next.After(after) {
+			next = at(t.Year(), t.Month(), t.Day()+days+7)
+		}
+	case bottypes.DigestMonthly:
+		if next = at(t.Year(), t.Month(), digest.Day); !next.After(after) {
+			next = at(t.Year(), t.Month()+1, digest.Day)
+		}
+	default:
+		if next = at(t.Year(), t.Month(), t.Day()); !next.After(after) {
+			next = at(t.Year(), t.Month(), t.Day()+1)
+		}
+	}
+	return next
+}
+
+// DigestPeriod Период регулярного отчета, отправляемого в момент runAt: прошлые день, неделя или месяц.
+func DigestPeriod(digest bottypes.Digest, runAt time.Time, loc *time.Location) (Period, error) {
+	var period timeutils.Period
+	switch digest.Frequency {
+	case bottypes.DigestWeekly:
+		period = timeutils.NewWeek(runAt, loc, time.Monday).Prev()
+	case bottypes.DigestMonthly:
+		period = timeutils.NewMonth(runAt, loc).Prev()
+	default:
+		period = timeutils.NewDay(runAt, loc).Prev()
+	}
+	return newRangePeriod(period.Start(), period.Last())
+}
@@ -0,0 +1,219 @@
+package reports
+
+// Периоды отчетов: последние неделя/месяц/год и календарные диапазоны дат.
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/timeutils"
+)
+
+const (
+	dateLayout    = "2006-01-02"
+	rangeKeyDate  = "20060102"
+	maxRangeYears = 10 // Максимальная длина диапазона отчета в годах.
+	prevKeyPrefix = "prev_"
+)
+
+var (
+	// Диапазон дат: "2024-01-01 2024-03-31".
+	rangeRegexp = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})\s+(\d{4}-\d{2}-\d{2})$`)
+	// Календарный год: "2023".
+	yearRegexp = regexp.MustCompile(`^\d{4}$`)
+	// Ключ диапазона дат в запросе: "20240101-20240331".
+	rangeKeyRegexp = regexp.MustCompile(`^(\d{8})-(\d{8})$`)
+)
+
+// Period Период отчета [From, To).
+type Period struct {
+	Key  string    // Ключ периода: w, m, y или диапазон дат (20240101-20240331).
+	From time.Time // Начало периода.
+	To   time.Time // Конец периода (не включается).
+}
+
+// Name Название периода для заголовка отчета.
+func (p Period) Name() string {
+	switch p.Key {
+	case "w":
+		return "последнюю неделю"
+	case "m":
+		return "последний месяц"
+	case "y":
+		return "последний год"
+	case prevKeyPrefix + "w":
+		return "предыдущую неделю"
+	case prevKeyPrefix + "m":
+		return "предыдущий месяц"
+	case prevKeyPrefix + "y":
+		return "предыдущий год"
+	}
+	last := p.To.AddDate(0, 0, -1)
+	if p.From.Equal(last) {
+		return p.From.Format(dateLayout)
+	}
+	return fmt.Sprintf("период с %v по %v", p.From.Format(dateLayout), last.Format(dateLayout))
+}
+
+// Prev Предыдущий период той же длины, заканчивающийся в начале периода.
+// Период с начала месяца сдвигается на затронутое им количество календарных месяцев: месяц, квартал или год
+// с его начала (в том числе незавершенные) сравниваются с теми же днями предыдущего месяца, квартала или года
+// (выбирается наименьший, содержащий период), но не дальше конца предыдущего периода.
+func (p Period) Prev() Period {
+	switch p.Key {
+	case "w":
+		return Period{Key: prevKeyPrefix + p.Key, From: p.From.AddDate(0, 0, -7), To: p.From}
+	case "m":
+		return Period{Key: prevKeyPrefix + p.Key, From: p.From.AddDate(0, -1, 0), To: p.From}
+	case "y":
+		return Period{Key: prevKeyPrefix + p.Key, From: p.From.AddDate(-1, 0, 0), To: p.From}
+	}
+
+	last := p.From.AddDate(0, 0, -1)
+	from := p.From.AddDate(0, 0, -int(math.Round(p.To.Sub(p.From).Hours()/24)))
+	if p.From.Day() == 1 {
+		// Календарные месяцы, которые затрагивает период.
+		periodLast := p.To.AddDate(0, 0, -1)
+		months := (periodLast.Year()-p.From.Year())*12 + int(periodLast.Month()-p.From.Month()) + 1
+		switch {
+		case months == 1:
+		case months <= 3 && (p.From.Month()-1)%3 == 0:
+			months = 3
+		case months <= 12 && p.From.Month() == time.January:
+			months = 12
+		}
+		from = p.From.AddDate(0, -months, 0)
+		shifted := addMonths(periodLast, -months)
+		if p.To.Day() == 1 {
+			// Период заканчивается в конце месяца: предыдущий период тоже заканчивается в конце месяца.
+			shifted = p.To.AddDate(0, -months, -1)
+		}
+		if shifted.Before(last) {
+			last = shifted
+		}
+	}
+	prev, err := newRangePeriod(from, last)
+	if err != nil {
+		return Period{From: from, To: p.From}
+	}
+	return prev
+}
+
+// addMonths Сдвиг даты на n месяцев с ограничением дня последним днем месяца (31 мая - 1 месяц = 30 апреля).
+func addMonths(t time.Time, n int) time.Time {
+	lastDay := time.Date(t.Year(), t.Month()+time.Month(n)+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	return time.Date(t.Year(), t.Month()+time.Month(n), min(t.Day(), lastDay), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// ToDate Часть периода по день now включительно, если период еще не закончился.
+// Используется для сравнения незавершенного периода с теми же днями предыдущего периода (см. Prev).
+func (p Period) ToDate(now time.Time) Period {
+	if !p.To.After(now) || now.Before(p.From) {
+		return p
+	}
+	period, err := newRangePeriod(p.From, now)
+	if err != nil {
+		return p
+	}
+	return period
+}
+
+// PeriodFromKey Период отчета по ключу периода (w - неделя, m - месяц, y - год, либо диапазон дат).
+func PeriodFromKey(key string, now time.Time) (Period, error) {
+	switch key {
+	case "w":
+		return Period{Key: key, From: now.AddDate(0, 0, -7), To: now}, nil
+	case "m":
+		return Period{Key: key, From: now.AddDate(0, -1, 0), To: now}, nil
+	case "y":
+		return Period{Key: key, From: now.AddDate(-1, 0, 0), To: now}, nil
+	}
+
+	matches := rangeKeyRegexp.FindStringSubmatch(key)
+	if matches == nil {
+		return Period{}, fmt.Errorf("unknown report period %q", key)
+	}
+	from, errFrom := time.ParseInLocation(rangeKeyDate, matches[1], now.Location())
+	last, errLast := time.ParseInLocation(rangeKeyDate, matches[2], now.Location())
+	if errFrom != nil || errLast != nil {
+		return Period{}, fmt.Errorf("incorrect report period %q", key)
+	}
+	return newRangePeriod(from, last)
+}
+
+// ParsePeriod Разбор периода отчета, введенного пользователем:
+// диапазон дат ("2024-01-01 2024-03-31"), год ("2023") или название периода ("last month", "этот квартал").
+func ParsePeriod(text string, now time.Time) (Period, error) {
+	text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+
+	if matches := rangeRegexp.FindStringSubmatch(text); matches != nil {
+		from, errFrom := time.ParseInLocation(dateLayout, matches[1], now.Location())
+		last, errLast := time.ParseInLocation(dateLayout, matches[2], now.Location())
+		if errFrom != nil || errLast != nil {
+			return Period{}, fmt.Errorf("incorrect report dates %q", text)
+		}
+		return newRangePeriod(from, last)
+	}
+
+	if yearRegexp.MatchString(text) {
+		year, _ := strconv.Atoi(text)
+		period := timeutils.NewYear(time.Date(year, time.January, 1, 0, 0, 0, 0, now.Location()), now.Location())
+		return newRangePeriod(period.Start(), period.Last())
+	}
+
+	loc := now.Location()
+	var period timeutils.Period
+	switch text {
+	case "today", "сегодня":
+		period = timeutils.NewDay(now, loc)
+	case "yesterday", "вчера":
+		period = timeutils.NewDay(now, loc).Prev()
+	case "this week", "эта неделя", "эту неделю":
+		period = timeutils.NewWeek(now, loc, time.Monday)
+	case "last week", "прошлая неделя", "прошлую неделю":
+		period = timeutils.NewWeek(now, loc, time.Monday).Prev()
+	case "this month", "этот месяц":
+		period = timeutils.NewMonth(now, loc)
+	case "last month", "прошлый месяц":
+		period = timeutils.NewMonth(now, loc).Prev()
+	case "this quarter", "этот квартал":
+		period = timeutils.NewQuarter(now, loc)
+	case "last quarter", "прошлый квартал":
+		period = timeutils.NewQuarter(now, loc).Prev()
+	case "this year", "этот год":
+		period = timeutils.NewYear(now, loc)
+	case "last year", "прошлый год":
+		period = timeutils.NewYear(now, loc).Prev()
+	default:
+		return Period{}, fmt.Errorf("unknown report period %q", text)
+	}
+	return newRangePeriod(period.Start(), period.Last())
+}
+
+// newRangePeriod Период с первого по последний день включительно.
+func newRangePeriod(from time.Time, last time.Time) (Period, error) {
+	if last.Before(from) {
+		return Period{}, fmt.Errorf("report period end %s is before start %s", last.Format(dateLayout), from.Format(dateLayout))
+	}
+	if last.After(from.AddDate(maxRangeYears, 0, 0)) {
+		return Period{}, fmt.Errorf("report period is longer than %d years", maxRangeYears)
+	}
+	return Period{
+		Key:  from.Format(rangeKeyDate) + "-" + last.Format(rangeKeyDate),
+		From: from,
+		To:   timeutils.NewDay(last, last.Location()).End(),
+	}, nil
+}
+
+// PeriodName Название периода отчета по ключу периода.
+func PeriodName(reportKey string) string {
+	period, err := PeriodFromKey(reportKey, time.Now())
+	if err != nil {
+		return reportKey
+	}
+	return period.Name()
+}
@@ -0,0 +1,91 @@
+package reports
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // Часовые пояса для тестов на системах без tzdata.
+)
+
+func TestPeriodToDatePrev(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Minsk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		now  string
+		text string
+		key  string // Период по текущий день.
+		prev string // Предыдущий период.
+	}{
+		{name: "month to date", now: "2024-10-19", text: "этот месяц", key: "20241001-20241019", prev: "20240901-20240919"},
+		{name: "month to date clamped", now: "2024-03-30", text: "этот месяц", key: "20240301-20240330", prev: "20240201-20240229"},
+		{name: "full month", now: "2024-03-31", text: "этот месяц", key: "20240301-20240331", prev: "20240201-20240229"},
+		{name: "first day of month", now: "2024-10-01", text: "этот месяц", key: "20241001-20241001", prev: "20240901-20240901"},
+		{name: "quarter to date", now: "2024-05-20", text: "этот квартал", key: "20240401-20240520", prev: "20240101-20240220"},
+		{name: "quarter to date clamped", now: "2024-05-31", text: "этот квартал", key: "20240401-20240531", prev: "20240101-20240229"},
+		{name: "quarter to date across year", now: "2024-02-20", text: "этот квартал", key: "20240101-20240220", prev: "20231001-20231120"},
+		{name: "year to date", now: "2024-10-19", text: "этот год", key: "20240101-20241019", prev: "20230101-20231019"},
+		{name: "year to date leap day", now: "2024-02-29", text: "этот год", key: "20240101-20240229", prev: "20231001-20231130"},
+		{name: "last quarter", now: "2024-05-20", text: "прошлый квартал", key: "20240101-20240331", prev: "20231001-20231231"},
+		{name: "last month", now: "2024-05-20", text: "прошлый месяц", key: "20240401-20240430", prev: "20240301-20240331"},
+		{name: "year", now: "2024-05-20", text: "2023", key: "20230101-20231231", prev: "20220101-20221231"},
+		{name: "months from month start", now: "2024-05-20", text: "2024-02-01 2024-04-20", key: "20240201-20240420", prev: "20231101-20240120"},
+		{name: "custom days", now: "2024-05-20", text: "2024-01-10 2024-01-19", key: "20240110-20240119", prev: "20231231-20240109"},
+		{name: "future range from month start", now: "2024-05-20", text: "2024-06-01 2024-06-10", key: "20240601-20240610", prev: "20240501-20240510"},
+		{name: "full months", now: "2024-05-20", text: "2024-02-01 2024-03-31", key: "20240201-20240331", prev: "20231201-20240131"},
+		{name: "second half year", now: "2025-01-15", text: "2024-07-01 2024-12-31", key: "20240701-20241231", prev: "20240101-20240630"},
+		{name: "first half year", now: "2025-01-15", text: "2024-01-01 2024-06-30", key: "20240101-20240630", prev: "20230101-20230630"},
+		{name: "half year to date", now: "2024-10-20", text: "2024-07-01 2024-12-31", key: "20240701-20241020", prev: "20240301-20240620"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, err := time.ParseInLocation("2006-01-02 15:04", tt.now+" 15:00", loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			period, err := ParsePeriod(tt.text, now)
+			if err != nil {
+				t.Fatalf("ParsePeriod(%q) error: %v", tt.text, err)
+			}
+			period = period.ToDate(now)
+			if period.Key != tt.key {
+				t.Errorf("ParsePeriod(%q).ToDate(%v) = %v, want %v", tt.text, tt.now, period.Key, tt.key)
+			}
+			prev := period.Prev()
+			if prev.Key != tt.prev {
+				t.Errorf("%v.Prev() = %v, want %v", period.Key, prev.Key, tt.prev)
+			}
+			if !prev.To.After(prev.From) || prev.To.After(period.From) {
+				t.Errorf("%v.Prev() = [%v, %v) does not end before the period", period.Key, prev.From, prev.To)
+			}
+		})
+	}
+}
+
+func TestRelativePeriodPrev(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		key  string
+		from time.Time
+	}{
+		{key: "w", from: now.AddDate(0, 0, -14)},
+		{key: "m", from: now.AddDate(0, -2, 0)},
+		{key: "y", from: now.AddDate(-2, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			period, err := PeriodFromKey(tt.key, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if period.ToDate(now) != period {
+				t.Errorf("PeriodFromKey(%q).ToDate() changed the period", tt.key)
+			}
+			prev := period.Prev()
+			if prev.Key != prevKeyPrefix+tt.key || !prev.From.Equal(tt.from) || !prev.To.Equal(period.From) {
+				t.Errorf("PeriodFromKey(%q).Prev() = %v [%v, %v)", tt.key, prev.Key, prev.From, prev.To)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package reports
+
+// Формат запроса на формирование отчета, общий для продюсера (бот) и обработчиков очереди.
+// Запрос передается в виде JSON с номером версии схемы. Новые поля добавляются только как необязательные,
+// поэтому обработчики старых версий игнорируют неизвестные поля и продолжают обрабатывать запросы.
+// Несовместимые изменения требуют увеличения MinCompatibleVersion.
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	// SchemaVersion Текущая версия схемы запроса.
+	SchemaVersion = 1
+	// MinCompatibleVersion Минимальная версия схемы, которую понимает обработчик.
+	MinCompatibleVersion = 1
+)
+
+// Форматы отчета.
+const (
+	FormatText = bottypes.ReportKindText // Текстовая таблица по категориям.
+	FormatPie  = bottypes.ReportKindPie  // Круговая диаграмма по категориям.
+	FormatBar  = bottypes.ReportKindBar  // График расходов по дням или месяцам.
+	// FormatCompare Сравнение расходов по категориям с предыдущим периодом.
+	FormatCompare = bottypes.ReportKindCompare
+)
+
+// DefaultLocale Язык отчета по умолчанию (для запросов без языка).
+const DefaultLocale = "ru"
+
+// Request Запрос на формирование отчета.
+type Request struct {
+	Version      int               `json:"version"`
+	RequestID    string            `json:"request_id"`
+	UserID       int64             `json:"user_id"`
+	Period       string            `json:"period,omitempty"`   // Ключ периода (w, m, y или диапазон дат) для заголовка и кэша отчета.
+	From         time.Time         `json:"from"`               // Начало периода.
+	To           time.Time         `json:"to"`                 // Конец периода (не включается).
+	Currency     string            `json:"currency,omitempty"` // Валюта сумм отчета (пустая - текущая валюта пользователя).
+	Format       string            `json:"format,omitempty"`
+	Locale       string            `json:"locale,omitempty"` // Язык отчета.
+	RequestedAt  time.Time         `json:"requested_at"`
+	TraceContext map[string]string `json:"trace_context,omitempty"` // Контекст трассировки (W3C traceparent).
+}
+
+// NewRequest Создание запроса на отчет за период с сохранением контекста трассировки.
+func NewRequest(ctx context.Context, userID int64, period Period, format string, currency string, now time.Time) (Request, error) {
+	requestID, err := newRequestID()
+	if err != nil {
+		return Request{}, err
+	}
+
+	traceContext := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, traceContext)
+
+	return Request{
+		Version:      SchemaVersion,
+		RequestID:    requestID,
+		UserID:       userID,
+		Period:       period.Key,
+		From:         period.From,
+		To:           period.To,
+		Currency:     currency,
+		Format:       format,
+		Locale:       DefaultLocale,
+		RequestedAt:  now,
+		TraceContext: traceContext,
+	}, nil
+}
+
+// Key Ключ сообщения в очереди (ID пользователя): запросы одного пользователя попадают в одну партицию.
+func (r Request) Key() string {
+	return strconv.FormatInt(r.UserID, 10)
+}
+
+// ReportPeriod Период отчета.
+func (r Request) ReportPeriod() Period {
+	return Period{Key: r.Period, From: r.From, To: r.To}
+}
+
+// Context Восстановление контекста трассировки из запроса.
+func (r Request) Context(ctx context.Context) context.Context {
+	if len(r.TraceContext) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(r.TraceContext))
+}
+
+// EncodeRequest Сериализация запроса для отправки в очередь.
+func EncodeRequest(r Request) (string, error) {
+	if r.Version == 0 {
+		r.Version = SchemaVersion
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("encode report request: %w", err)
+	}
+	return string(data), nil
+}
+
+// DecodeRequest Разбор запроса из сообщения очереди.
+// Сообщения старого формата (ключ - ID пользователя, значение - ключ периода) преобразуются в запрос.
+func DecodeRequest(key string, value string, now time.Time) (Request, error) {
+	if !strings.HasPrefix(strings.TrimSpace(value), "{") {
+		return decodeLegacyRequest(key, value, now)
+	}
+
+	var r Request
+	if err := json.Unmarshal([]byte(value), &r); err != nil {
+		return Request{}, fmt.Errorf("decode report request: %w", err)
+	}
+	if r.Version < MinCompatibleVersion {
+		return Request{}, fmt.Errorf("unsupported report request version %d", r.Version)
+	}
+	if r.UserID == 0 {
+		return Request{}, fmt.Errorf("report request %q has no user id", r.RequestID)
+	}
+	if r.From.IsZero() || r.To.IsZero() || r.To.Before(r.From) {
+		return Request{}, fmt.Errorf("report request %q has incorrect period", r.RequestID)
+	}
+	if r.Format == "" {
+		r.Format = FormatText
+	}
+	if r.Locale == "" {
+		r.Locale = DefaultLocale
+	}
+	return r, nil
+}
+
+func decodeLegacyRequest(key string, value string, now time.Time) (Request, error) {
+	userID, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return Request{}, fmt.Errorf("incorrect user id %q: %w", key, err)
+	}
+
+	// Бот старых версий отправлял команду без "report_": "/w", "/m" или "/y".
+	period, err := PeriodFromKey(strings.TrimPrefix(strings.TrimSpace(value), "/"), now)
+	if err != nil {
+		return Request{}, err
+	}
+
+	return Request{
+		Version:     0,
+		UserID:      userID,
+		Period:      period.Key,
+		From:        period.From,
+		To:          period.To,
+		Format:      FormatText,
+		Locale:      DefaultLocale,
+		RequestedAt: now,
+	}, nil
+}
+
+func newRequestID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate report request id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
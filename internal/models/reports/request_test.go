@@ -0,0 +1,95 @@
+package reports
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDecodeLegacyRequest(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		key   string
+		from  time.Time
+	}{
+		{name: "week", value: "/w", key: "w", from: now.AddDate(0, 0, -7)},
+		{name: "month", value: "/m", key: "m", from: now.AddDate(0, -1, 0)},
+		{name: "year", value: "/y", key: "y", from: now.AddDate(-1, 0, 0)},
+		{name: "without slash", value: "m", key: "m", from: now.AddDate(0, -1, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := DecodeRequest("123", tt.value, now)
+			if err != nil {
+				t.Fatalf("DecodeRequest(%q) error: %v", tt.value, err)
+			}
+			if req.UserID != 123 || req.Period != tt.key || req.Format != FormatText || req.Locale != DefaultLocale {
+				t.Errorf("DecodeRequest(%q) = user %v, period %q, format %q, locale %q", tt.value, req.UserID, req.Period, req.Format, req.Locale)
+			}
+			if !req.From.Equal(tt.from) || !req.To.Equal(now) {
+				t.Errorf("DecodeRequest(%q) period = [%v, %v), want [%v, %v)", tt.value, req.From, req.To, tt.from, now)
+			}
+		})
+	}
+}
+
+func TestDecodeLegacyRequestErrors(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{name: "bad user id", key: "abc", value: "/m"},
+		{name: "unknown period", key: "123", value: "/d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeRequest(tt.key, tt.value, now); err == nil {
+				t.Errorf("DecodeRequest(%q, %q) error = nil", tt.key, tt.value)
+			}
+		})
+	}
+}
+
+// Запросы первой версии схемы без языка и формата получают значения по умолчанию.
+func TestDecodeRequestDefaults(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	value := `{"version":1,"request_id":"abc","user_id":123,"period":"m","from":"2024-02-15T12:00:00Z","to":"2024-03-15T12:00:00Z","requested_at":"2024-03-15T12:00:00Z"}`
+	req, err := DecodeRequest("123", value, now)
+	if err != nil {
+		t.Fatalf("DecodeRequest(%s) error: %v", value, err)
+	}
+	if req.Format != FormatText || req.Locale != DefaultLocale {
+		t.Errorf("DecodeRequest(%s) format %q, locale %q", value, req.Format, req.Locale)
+	}
+}
+
+func TestEncodeDecodeRequest(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	period, err := PeriodFromKey("20240101-20240331", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := NewRequest(context.Background(), 123, period, FormatPie, "USD", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := EncodeRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeRequest(req.Key(), value, now)
+	if err != nil {
+		t.Fatalf("DecodeRequest(%s) error: %v", value, err)
+	}
+	if decoded.RequestID != req.RequestID || decoded.UserID != 123 || decoded.Period != period.Key ||
+		decoded.Currency != "USD" || decoded.Format != FormatPie || decoded.Locale != DefaultLocale || decoded.Version != SchemaVersion {
+		t.Errorf("DecodeRequest(%s) = %+v", value, decoded)
+	}
+	if !decoded.From.Equal(period.From) || !decoded.To.Equal(period.To) || !decoded.RequestedAt.Equal(now) {
+		t.Errorf("DecodeRequest(%s) period = [%v, %v), requested at %v", value, decoded.From, decoded.To, decoded.RequestedAt)
+	}
+}
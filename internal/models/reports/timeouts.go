@@ -0,0 +1,64 @@
+package reports
+
+import (
+	"context"
+	"time"
+
+	"github.com/shoksin/financesBot/internal/helpers/tgfmt"
+	"github.com/shoksin/financesBot/internal/logger"
+	"github.com/shoksin/financesBot/internal/models/bottypes"
+)
+
+const txtReportTimeout = "Не удалось сформировать отчет за %v: превышено время ожидания. Попробуйте запросить отчет еще раз."
+
+// TimeoutStorage Интерфейс хранилища запросов на формирование отчетов.
+type TimeoutStorage interface {
+	TimeoutReportRequests(ctx context.Context, deadline time.Duration) ([]bottypes.ReportRequestStatus, error)
+}
+
+// MessagesSender Интерфейс для отправки сообщений пользователю.
+type MessagesSender interface {
+	SendMessage(userID int64, text string) error
+}
+
+// TimeoutChecker Поиск зависших запросов на формирование отчетов и уведомление пользователей.
+type TimeoutChecker struct {
+	storage  TimeoutStorage
+	sender   MessagesSender
+	deadline time.Duration
+}
+
+func NewTimeoutChecker(storage TimeoutStorage, sender MessagesSender, deadline time.Duration) *TimeoutChecker {
+	return &TimeoutChecker{storage: storage, sender: sender, deadline: deadline}
+}
+
+// CheckTimeouts Перевод просроченных запросов в статус timeout и отправка уведомлений.
+func (c *TimeoutChecker) CheckTimeouts(ctx context.Context) {
+	requests, err := c.storage.TimeoutReportRequests(ctx, c.deadline)
+	if err != nil {
+		logger.Error("Error checking report request timeouts", "err", err)
+		return
+	}
+
+	for _, req := range requests {
+		logger.Warning("Report request timed out", "requestID", req.ID, "userID", req.UserID, "err", req.Error)
+		if err := c.sender.SendMessage(req.UserID, tgfmt.Sprintf(txtReportTimeout, PeriodName(req.Period))); err != nil {
+			logger.Error("Error sending report timeout message", "requestID", req.ID, "err", err)
+		}
+	}
+}
+
+// AutoCheckTimeouts Периодическая проверка просроченных запросов до отмены контекста.
+func (c *TimeoutChecker) AutoCheckTimeouts(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.CheckTimeouts(ctx)
+		}
+	}
+}
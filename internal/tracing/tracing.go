@@ -12,6 +12,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
@@ -43,6 +44,8 @@ func init() {
 	)
 
 	otel.SetTracerProvider(tp)
+	// Передача контекста трассировки в запросах на формирование отчетов.
+	otel.SetTextMapPropagator(propagation.TraceContext{})
 	tracer = otel.Tracer("tg-bot")
 }
 
@@ -51,10 +54,17 @@ func TracingMiddleware(next tg.HandlerFunc) tg.HandlerFunc {
 		ctx, span := tracer.Start(msgModel.GetCtx(), "ProcessingMessages")
 		defer span.End()
 
-		span.SetAttributes(
-			attribute.String("chat.id", fmt.Sprintf("%d", tgUpdate.Message.Chat.ID)),
-			attribute.String("message.id", fmt.Sprintf("%d", tgUpdate.Message.MessageID)),
-		)
+		// Для нажатия кнопки атрибуты берутся из сообщения, к которому относится кнопка.
+		tgMessage := tgUpdate.Message
+		if tgMessage == nil && tgUpdate.CallbackQuery != nil {
+			tgMessage = tgUpdate.CallbackQuery.Message
+		}
+		if tgMessage != nil {
+			span.SetAttributes(
+				attribute.String("chat.id", fmt.Sprintf("%d", tgMessage.Chat.ID)),
+				attribute.String("message.id", fmt.Sprintf("%d", tgMessage.MessageID)),
+			)
+		}
 
 		traceID := span.SpanContext().TraceID().String()
 		logger.Info("start span trace", "traceId", traceID)
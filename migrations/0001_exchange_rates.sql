@@ -0,0 +1,11 @@
+-- Курсы валют относительно основной валюты (последние известные курсы и история по датам).
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    main_currency VARCHAR(3)      NOT NULL,
+    currency      VARCHAR(3)      NOT NULL,
+    rate          NUMERIC(30, 12) NOT NULL, -- Количество единиц валюты за 1 единицу основной.
+    rates_date    DATE            NOT NULL, -- Дата, на которую источник установил курс.
+    updated_at    TIMESTAMPTZ     NOT NULL, -- Время получения курса из источника.
+    PRIMARY KEY (main_currency, currency, rates_date)
+);
+
+CREATE INDEX IF NOT EXISTS exchange_rates_updated_at_idx ON exchange_rates (main_currency, updated_at);
@@ -0,0 +1,27 @@
+-- Пользователи, категории и записи о расходах. Суммы хранятся в NUMERIC, валюта - отдельным полем.
+CREATE TABLE IF NOT EXISTS users (
+    id       SERIAL PRIMARY KEY,
+    tg_id    BIGINT         NOT NULL UNIQUE,
+    name     TEXT           NOT NULL DEFAULT '',
+    currency VARCHAR(3)     NOT NULL,           -- Валюта пользователя для ввода и отчетов.
+    limits   NUMERIC(20, 4) NOT NULL DEFAULT 0  -- Ежемесячный бюджет в основной валюте (0 - без ограничений).
+);
+
+CREATE TABLE IF NOT EXISTS usercategories (
+    id      SERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (tg_id) ON DELETE CASCADE,
+    name    TEXT   NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS userdata (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT         NOT NULL REFERENCES users (tg_id) ON DELETE CASCADE,
+    category_id INTEGER        NOT NULL REFERENCES usercategories (id) ON DELETE CASCADE,
+    sum         NUMERIC(20, 4) NOT NULL, -- Сумма в основной валюте.
+    currency    VARCHAR(3)     NOT NULL,
+    period      TIMESTAMPTZ    NOT NULL, -- Дата расхода.
+    created_at  TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS userdata_user_period_idx ON userdata (user_id, period);
@@ -0,0 +1,14 @@
+-- Подписки пользователей на изменение курсов валют.
+CREATE TABLE IF NOT EXISTS rate_alerts (
+    id              SERIAL PRIMARY KEY,
+    user_id         BIGINT          NOT NULL REFERENCES users (tg_id) ON DELETE CASCADE,
+    currency        VARCHAR(3)      NOT NULL,              -- Отслеживаемая валюта.
+    base_currency   VARCHAR(3)      NOT NULL,              -- Валюта, в которой выражен курс.
+    kind            VARCHAR(10)     NOT NULL,              -- above, below или change.
+    threshold       NUMERIC(30, 12) NOT NULL,              -- Пороговый курс или процент изменения.
+    triggered       BOOLEAN         NOT NULL DEFAULT FALSE, -- Условие выполнено, уведомление отправлено.
+    last_rates_date DATE,                                  -- Дата курсов последнего уведомления об изменении.
+    created_at      TIMESTAMPTZ     NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS rate_alerts_user_idx ON rate_alerts (user_id);
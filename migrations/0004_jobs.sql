@@ -0,0 +1,18 @@
+-- Очередь фоновых задач (альтернатива Kafka). Задачи забираются через FOR UPDATE SKIP LOCKED,
+-- о новых задачах обработчики узнают через LISTEN/NOTIFY на канале jobs.
+CREATE TABLE IF NOT EXISTS jobs (
+    id           BIGSERIAL PRIMARY KEY,
+    queue        VARCHAR(100) NOT NULL,                   -- Имя очереди (топик).
+    key          TEXT         NOT NULL DEFAULT '',
+    payload      TEXT         NOT NULL,
+    status       VARCHAR(10)  NOT NULL DEFAULT 'pending', -- pending, running, done, dead.
+    attempts     INTEGER      NOT NULL DEFAULT 0,
+    max_attempts INTEGER      NOT NULL,
+    run_at       TIMESTAMPTZ  NOT NULL DEFAULT now(),     -- Время, не раньше которого задачу можно выполнять.
+    locked_until TIMESTAMPTZ,                             -- Время, после которого зависшую задачу можно забрать повторно.
+    last_error   TEXT,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS jobs_ready_idx ON jobs (queue, run_at) WHERE status IN ('pending', 'running');
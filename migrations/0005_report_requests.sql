@@ -0,0 +1,17 @@
+-- Состояние запросов на формирование отчетов. Пока запрос в очереди или выполняется (queued, running),
+-- повторный запрос того же отчета по тем же данным (data_version - последняя запись расходов) не создается.
+CREATE TABLE IF NOT EXISTS report_requests (
+    id           VARCHAR(32) PRIMARY KEY,                 -- ID запроса (request_id сообщения очереди).
+    user_id      BIGINT      NOT NULL REFERENCES users (tg_id) ON DELETE CASCADE,
+    period       VARCHAR(10) NOT NULL,                    -- Ключ периода отчета.
+    data_version BIGINT      NOT NULL,                    -- ID последней записи расходов пользователя на момент запроса.
+    status       VARCHAR(10) NOT NULL DEFAULT 'queued',   -- queued, running, done, failed, timeout.
+    last_error   TEXT        NOT NULL DEFAULT '',
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS report_requests_in_flight_idx ON report_requests (user_id, period, data_version)
+    WHERE status IN ('queued', 'running');
+
+CREATE INDEX IF NOT EXISTS report_requests_user_idx ON report_requests (user_id, requested_at);
@@ -0,0 +1,2 @@
+-- Ключ периода отчета может содержать диапазон дат (20240101-20240331).
+ALTER TABLE report_requests ALTER COLUMN period TYPE VARCHAR(20);
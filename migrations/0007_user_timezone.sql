@@ -0,0 +1,2 @@
+-- Часовой пояс пользователя (имя из базы IANA, например Europe/Minsk). Пустая строка - часовой пояс по умолчанию из настроек.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT '';
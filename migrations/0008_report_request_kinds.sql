@@ -0,0 +1,6 @@
+-- Вид отчета (text, pie, bar): диаграмму можно запросить, пока формируется текстовый отчет за тот же период.
+ALTER TABLE report_requests ADD COLUMN IF NOT EXISTS kind VARCHAR(10) NOT NULL DEFAULT 'text';
+
+DROP INDEX IF EXISTS report_requests_in_flight_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS report_requests_in_flight_idx ON report_requests (user_id, period, kind, data_version)
+    WHERE status IN ('queued', 'running');
@@ -0,0 +1,4 @@
+-- Сумма расхода в валюте ввода и комментарий (для выгрузки записей). Для старых записей исходная сумма не известна.
+ALTER TABLE userdata ADD COLUMN IF NOT EXISTS original_sum      NUMERIC(20, 4);
+ALTER TABLE userdata ADD COLUMN IF NOT EXISTS original_currency VARCHAR(3);
+ALTER TABLE userdata ADD COLUMN IF NOT EXISTS comment           TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+-- Сопоставление колонок банковских выписок CSV, сохраненное пользователем для каждого банка.
+CREATE TABLE IF NOT EXISTS import_mappings (
+    user_id            BIGINT      NOT NULL REFERENCES users (tg_id) ON DELETE CASCADE,
+    bank_key           VARCHAR(64) NOT NULL,              -- Отпечаток заголовка выписки.
+    date_column        INT         NOT NULL,
+    amount_column      INT         NOT NULL,
+    description_column INT         NOT NULL DEFAULT -1,   -- -1, если колонки с описанием нет.
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, bank_key)
+);
@@ -0,0 +1,4 @@
+-- Идентификатор операции из загруженной выписки (FITID для OFX) для защиты от повторной загрузки.
+ALTER TABLE userdata ADD COLUMN IF NOT EXISTS external_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS userdata_user_external_id_idx ON userdata (user_id, external_id) WHERE external_id IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Сопоставление категорий из выгрузок других приложений с категориями пользователя.
+CREATE TABLE IF NOT EXISTS import_category_mappings (
+    user_id         BIGINT      NOT NULL REFERENCES users (tg_id) ON DELETE CASCADE,
+    source_category TEXT        NOT NULL,                 -- Категория из файла в нижнем регистре.
+    category        TEXT        NOT NULL,                 -- Категория пользователя.
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, source_category)
+);
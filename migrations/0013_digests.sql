@@ -0,0 +1,16 @@
+-- Подписки пользователей на регулярные отчеты (дайджесты).
+-- Время следующей отправки хранится в таблице, поэтому расписание переживает перезапуск бота.
+CREATE TABLE IF NOT EXISTS digests (
+    id          SERIAL PRIMARY KEY,
+    user_id     BIGINT      NOT NULL REFERENCES users (tg_id) ON DELETE CASCADE,
+    frequency   VARCHAR(10) NOT NULL,           -- daily, weekly или monthly.
+    weekday     SMALLINT    NOT NULL DEFAULT 0, -- День недели еженедельного отчета (0 - воскресенье).
+    day         SMALLINT    NOT NULL DEFAULT 0, -- День месяца ежемесячного отчета (1-28).
+    send_time   SMALLINT    NOT NULL,           -- Время отправки в минутах от начала дня в часовом поясе пользователя.
+    next_run_at TIMESTAMPTZ NOT NULL,           -- Время следующей отправки.
+    last_run_at TIMESTAMPTZ,                    -- Время последней отправки.
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, frequency, weekday, day, send_time)
+);
+
+CREATE INDEX IF NOT EXISTS digests_next_run_idx ON digests (next_run_at);